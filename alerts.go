@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultAlertCooldown is how long an alert of one kind stays muted after it
+// has been posted, so a burst of failing calls produces a single message.
+const defaultAlertCooldown = 30 * time.Minute
+
+// Alert kinds, used to de-duplicate messages.
+const (
+	alertKindAuth               = "auth"
+	alertKindRateLimit          = "rate_limit"
+	alertKindServiceUnavailable = "service_unavailable"
+	alertKindKeyExpiry          = "key_expiry"
+)
+
+// AdminAlerter posts operational problems (expired key, rate limiting, Riot
+// outages) to the admin channel, pinging the configured admin user or role.
+type AdminAlerter struct {
+	discord   *discordgo.Session
+	channelID string
+	mention   string
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// NewAdminAlerter creates an alerter for channelID. adminUserID and adminRoleID
+// are optional; the role wins when both are set.
+func NewAdminAlerter(discord *discordgo.Session, channelID, adminUserID, adminRoleID string) *AdminAlerter {
+	var mention string
+	switch {
+	case adminRoleID != "":
+		mention = fmt.Sprintf("<@&%s>", adminRoleID)
+	case adminUserID != "":
+		mention = fmt.Sprintf("<@%s>", adminUserID)
+	}
+
+	return &AdminAlerter{
+		discord:   discord,
+		channelID: channelID,
+		mention:   mention,
+		cooldown:  defaultAlertCooldown,
+		lastSent:  make(map[string]time.Time),
+	}
+}
+
+// Hooks wires the alerter into a RiotAPI's error notifications.
+func (a *AdminAlerter) Hooks() RiotAPIHooks {
+	return RiotAPIHooks{
+		OnAuthFailure:        a.AuthFailure,
+		OnRateLimited:        a.RateLimited,
+		OnServiceUnavailable: a.ServiceUnavailable,
+	}
+}
+
+func (a *AdminAlerter) AuthFailure(ctx context.Context, err *APIError) {
+	admin := a.mention
+	if admin == "" {
+		admin = "admin"
+	}
+
+	var message string
+	if userID := requesterFromContext(ctx); userID != "" {
+		message = fmt.Sprintf("我真是服了，%s还不rotate key吗, <@%s>啥都用不了", admin, userID)
+	} else {
+		message = fmt.Sprintf("🔑 %s Riot rejected the API key (status %d), game monitoring is paused until it is rotated", admin, err.StatusCode)
+	}
+	a.send(alertKindAuth, message)
+}
+
+func (a *AdminAlerter) RateLimited(ctx context.Context, err *APIError) {
+	a.send(alertKindRateLimit, fmt.Sprintf("⏳ %s Riot is still rate limiting %s after retries (retry after %s)",
+		a.alertPrefix(), err.Endpoint, err.RetryAfter))
+}
+
+func (a *AdminAlerter) ServiceUnavailable(ctx context.Context, err *APIError) {
+	a.send(alertKindServiceUnavailable, fmt.Sprintf("🔧 %s Riot API is failing on %s (status %d)",
+		a.alertPrefix(), err.Endpoint, err.StatusCode))
+}
+
+// Notify posts an arbitrary admin message, de-duplicated by kind.
+func (a *AdminAlerter) Notify(kind, message string) {
+	a.send(kind, fmt.Sprintf("%s %s", a.alertPrefix(), message))
+}
+
+// Clear re-arms alerts of kind, e.g. once the problem behind them is fixed.
+func (a *AdminAlerter) Clear(kind string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.lastSent, kind)
+}
+
+func (a *AdminAlerter) alertPrefix() string {
+	if a.mention == "" {
+		return "⚠️"
+	}
+	return a.mention
+}
+
+func (a *AdminAlerter) send(kind, message string) {
+	a.mu.Lock()
+	if last, ok := a.lastSent[kind]; ok && time.Since(last) < a.cooldown {
+		a.mu.Unlock()
+		return
+	}
+	// Entries past their cooldown no longer suppress anything; dropping
+	// them keeps per-match kinds from piling up.
+	for other, last := range a.lastSent {
+		if time.Since(last) >= a.cooldown {
+			delete(a.lastSent, other)
+		}
+	}
+	a.lastSent[kind] = time.Now()
+	a.mu.Unlock()
+
+	if a.discord == nil || a.channelID == "" {
+		log.Printf("Admin alert (%s): %s", kind, message)
+		return
+	}
+
+	if _, err := a.discord.ChannelMessageSend(a.channelID, message); err != nil {
+		log.Printf("Error sending admin alert: %v", err)
+	}
+}
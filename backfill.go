@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxBackfillGames caps one backfill, and is the limit when only a
+	// number of days is asked for.
+	maxBackfillGames    = 500
+	maxBackfillDays     = 365
+	defaultBackfillDays = 30
+
+	// backfillPageSize is how many match IDs are requested per page, the
+	// most match-v5 allows.
+	backfillPageSize = 100
+
+	// backfillPollInterval is how often unfinished backfills are retried
+	// when nothing new was queued, e.g. after Riot errors.
+	backfillPollInterval = time.Minute
+
+	// backfillProgressEvery is how many matches pass between progress edits.
+	backfillProgressEvery = 10
+
+	// interactionTokenLifetime is how long Discord accepts edits through an
+	// interaction's token.
+	interactionTokenLifetime = 15 * time.Minute
+)
+
+// Backfiller imports tracked players' past matches in the background, one
+// job at a time, so /stats has history right away. Jobs live in the
+// database and resume where they stopped after a restart. Requests go
+// through the shared RiotAPI rate limiter, so a backfill only slows the game
+// monitor down rather than tripping Riot's limits.
+type Backfiller struct {
+	db      Store
+	riotAPI *RiotAPI
+	discord *discordgo.Session
+
+	wake   chan struct{}
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewBackfiller(db Store, riotAPI *RiotAPI, discord *discordgo.Session) *Backfiller {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Backfiller{
+		db:      db,
+		riotAPI: riotAPI,
+		discord: discord,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (b *Backfiller) Start() {
+	go b.run()
+	log.Println("Backfiller started")
+}
+
+// Stop cancels the running job, which resumes on the next start.
+func (b *Backfiller) Stop() {
+	b.cancel()
+	<-b.done
+	log.Println("Backfiller stopped")
+}
+
+// Enqueue stores job and wakes the worker.
+func (b *Backfiller) Enqueue(job *BackfillJob) error {
+	if err := b.db.AddBackfillJob(job); err != nil {
+		return err
+	}
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (b *Backfiller) run() {
+	defer close(b.done)
+	for {
+		b.runPending()
+
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-b.wake:
+		case <-time.After(backfillPollInterval):
+		}
+	}
+}
+
+func (b *Backfiller) runPending() {
+	jobs, err := b.db.GetPendingBackfills()
+	if err != nil {
+		log.Printf("Error getting pending backfills: %v", err)
+		return
+	}
+
+	for idx := range jobs {
+		if b.ctx.Err() != nil {
+			return
+		}
+		if err := b.runJob(&jobs[idx]); err != nil && b.ctx.Err() == nil {
+			log.Printf("Backfill %d paused, retrying later: %v", jobs[idx].ID, err)
+		}
+	}
+}
+
+// runJob walks a player's match history from job.NextIndex, storing every
+// match. Errors worth retrying are returned with the job left pending;
+// anything else finishes it.
+func (b *Backfiller) runJob(job *BackfillJob) error {
+	player, err := b.db.GetTrackedPlayer(job.PUUID)
+	if err != nil {
+		return err
+	}
+	if player == nil {
+		// Untracking deletes the job too; this only races with it
+		return nil
+	}
+
+	for job.NextIndex < job.MaxGames {
+		count := min(backfillPageSize, job.MaxGames-job.NextIndex)
+		page, err := b.riotAPI.GetMatchIDs(b.ctx, player.Platform, player.PUUID, MatchHistoryQuery{
+			Start:     job.NextIndex,
+			Count:     count,
+			StartTime: job.Since,
+		})
+		if err != nil {
+			if errors.Is(err, ErrNotFound) || errors.Is(err, ErrBadRequest) {
+				return b.finish(job, *player, BackfillStatusFailed, err)
+			}
+			return err
+		}
+
+		for _, matchID := range page {
+			if err := b.importMatch(*player, matchID); err != nil {
+				if !isPermanentMatchError(err) {
+					return err
+				}
+				log.Printf("Backfill %d: skipping match %s: %v", job.ID, matchID, err)
+			} else {
+				job.Stored++
+				backfillMatchesImported.Inc()
+			}
+
+			job.NextIndex++
+			if err := b.db.UpdateBackfillProgress(job.ID, job.NextIndex, job.Stored); err != nil {
+				return err
+			}
+			if job.NextIndex%backfillProgressEvery == 0 {
+				b.reportProgress(job, *player)
+			}
+		}
+
+		if len(page) < count {
+			break
+		}
+	}
+
+	return b.finish(job, *player, BackfillStatusDone, nil)
+}
+
+// importMatch stores matchID and player's row in it. Nothing is posted:
+// these games are history, not news.
+func (b *Backfiller) importMatch(player TrackedPlayer, matchID string) error {
+	match, err := loadMatch(b.ctx, b.db, b.riotAPI, player.Platform, matchID)
+	if err != nil {
+		return err
+	}
+
+	matchData := match.PlayerData(player.PUUID)
+	if matchData == nil {
+		return errPlayerNotInMatch
+	}
+	return b.db.AddMatchData(matchData)
+}
+
+func (b *Backfiller) finish(job *BackfillJob, player TrackedPlayer, status string, cause error) error {
+	lastError := ""
+	if cause != nil {
+		lastError = cause.Error()
+	}
+	if err := b.db.FinishBackfill(job.ID, status, lastError); err != nil {
+		return err
+	}
+	job.Status = status
+	job.LastError = lastError
+
+	log.Printf("Backfill %d of %s#%s %s: %d matches imported", job.ID, player.GameName, player.TagLine, status, job.Stored)
+	b.reportProgress(job, player)
+	return nil
+}
+
+// reportProgress edits the job's message. Discord stops accepting edits
+// once the interaction token expires; the job carries on regardless.
+func (b *Backfiller) reportProgress(job *BackfillJob, player TrackedPlayer) {
+	if job.InteractionToken == "" || job.MessageID == "" || time.Since(job.CreatedAt) > interactionTokenLifetime {
+		return
+	}
+
+	content := backfillProgressText(job, player)
+	interaction := &discordgo.Interaction{AppID: job.AppID, Token: job.InteractionToken}
+	if _, err := b.discord.FollowupMessageEdit(interaction, job.MessageID, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		log.Printf("Error editing progress of backfill %d: %v", job.ID, err)
+	}
+}
+
+func backfillProgressText(job *BackfillJob, player TrackedPlayer) string {
+	scope := fmt.Sprintf("up to %d games", job.MaxGames)
+	if !job.Since.IsZero() {
+		scope = fmt.Sprintf("since %s, %s", job.Since.Format("Jan 2"), scope)
+	}
+
+	switch job.Status {
+	case BackfillStatusDone:
+		return fmt.Sprintf("✅ Imported %d past games of %s#%s (%s)", job.Stored, player.GameName, player.TagLine, scope)
+	case BackfillStatusFailed:
+		return fmt.Sprintf("❌ Importing past games of %s#%s failed after %d games: %s",
+			player.GameName, player.TagLine, job.Stored, job.LastError)
+	}
+	return fmt.Sprintf("⏳ Importing past games of %s#%s (%s): %d checked, %d imported so far",
+		player.GameName, player.TagLine, scope, job.NextIndex, job.Stored)
+}
+
+// newBackfillJob covers the last days days (all history if zero), at most
+// games games (maxBackfillGames if zero).
+func newBackfillJob(puuid string, days, games int) *BackfillJob {
+	if games <= 0 || games > maxBackfillGames {
+		games = maxBackfillGames
+	}
+	job := &BackfillJob{PUUID: puuid, MaxGames: games, Status: BackfillStatusPending}
+	if days > 0 {
+		job.Since = time.Now().AddDate(0, 0, -days)
+	}
+	return job
+}
+
+// startBackfill queues a backfill of player's history and posts the message
+// that shows its progress.
+func startBackfill(c *CommandContext, player TrackedPlayer, days, games int) error {
+	active, err := db.GetActiveBackfill(player.PUUID)
+	if err != nil {
+		return fmt.Errorf("checking backfills: %w", err)
+	}
+	if active != nil {
+		_, err := c.FollowUp(fmt.Sprintf("⏳ Past games of %s#%s are already being imported (%d checked so far)",
+			player.GameName, player.TagLine, active.NextIndex))
+		return err
+	}
+
+	job := newBackfillJob(player.PUUID, days, games)
+	message, err := c.FollowUp(backfillProgressText(job, player))
+	if err != nil {
+		return err
+	}
+
+	job.AppID = c.Interaction.AppID
+	job.InteractionToken = c.Interaction.Token
+	job.MessageID = message.ID
+	if err := backfiller.Enqueue(job); err != nil {
+		return fmt.Errorf("queueing backfill: %w", err)
+	}
+	return nil
+}
+
+// trackBackfill starts the backfill /track's options ask for, if any.
+func trackBackfill(c *CommandContext, player TrackedPlayer) error {
+	days := c.IntOption("backfill_days", 0)
+	games := c.IntOption("backfill_games", 0)
+	if days <= 0 && games <= 0 {
+		return nil
+	}
+	return startBackfill(c, player, days, games)
+}
+
+func handleBackfillCommand(c *CommandContext) error {
+	gameName, tagLine, err := c.RiotIDOption("summoner")
+	if err != nil {
+		return err
+	}
+	player, err := guildPlayerByRiotID(c.GuildID(), gameName, tagLine)
+	if err != nil {
+		return err
+	}
+
+	return startBackfill(c, *player, c.IntOption("days", defaultBackfillDays), c.IntOption("games", 0))
+}
+
+var minBackfillLimit = 1.0
+
+// backfillLimitOptions are the days and games options of /track and
+// /backfill, named with prefix.
+func backfillLimitOptions(prefix, daysDescription, gamesDescription string) []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        prefix + "days",
+			Description: daysDescription,
+			MinValue:    &minBackfillLimit,
+			MaxValue:    maxBackfillDays,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        prefix + "games",
+			Description: gamesDescription,
+			MinValue:    &minBackfillLimit,
+			MaxValue:    maxBackfillGames,
+		},
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Command pairs a slash command definition with its handler and the settings
+// the router's middleware enforce. The commands registry below is the only
+// place commands are declared: it is what gets registered with Discord and
+// what the router dispatches on.
+type Command struct {
+	*discordgo.ApplicationCommand
+	Handler HandlerFunc
+	// Autocomplete answers autocomplete interactions for the command's
+	// options, if any have Autocomplete set.
+	Autocomplete HandlerFunc
+
+	// GuildOnly commands are rejected (and hidden) in DMs.
+	GuildOnly bool
+	// Permission, if set, must allow the invoking member.
+	Permission func(i *discordgo.InteractionCreate) bool
+	// Defer acknowledges the interaction before the handler runs, for
+	// commands that call the Riot API.
+	Defer bool
+	// Public responses are visible to the whole channel; the rest are
+	// ephemeral.
+	Public bool
+}
+
+var commands = []Command{
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "help",
+			Description: "Show help information",
+		},
+		Handler: handleHelpCommand,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "pn",
+			Description: "Get the latest League of Legends patch notes",
+		},
+		Handler: handlePatchNotesCommand,
+		Public:  true,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "patchnotes",
+			Description: "Get the latest League of Legends patch notes",
+		},
+		Handler: handlePatchNotesCommand,
+		Public:  true,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "track",
+			Description: "Track a League of Legends player",
+			Options: append([]*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "summoner",
+					Description: "Summoner name (e.g., PlayerName#TAG)",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "region",
+					Description: "Server the player plays on (default: NA1)",
+					Required:    false,
+					Choices:     platformChoices(),
+				},
+			}, backfillLimitOptions("backfill_",
+				"Also import the player's games from this many past days",
+				"Also import up to this many of the player's past games")...),
+		},
+		Handler:   handleTrackCommand,
+		GuildOnly: true,
+		Defer:     true,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "untrack",
+			Description: "Stop tracking a League of Legends player",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "summoner",
+					Description:  "Summoner name (e.g., PlayerName#TAG)",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		Handler:      handleUntrackCommand,
+		Autocomplete: autocompleteTrackedPlayers,
+		GuildOnly:    true,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "stats",
+			Description: "Show stats for a tracked player",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "summoner",
+					Description:  "Summoner name (e.g., PlayerName#TAG)",
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "days",
+					Description: "Number of days to look back (default: 7)",
+					Required:    false,
+					MinValue:    &minStatsDays,
+					MaxValue:    maxStatsDays,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "queue",
+					Description: "Only count games from this queue (default: all)",
+					Required:    false,
+					Choices:     queueFilterChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "view",
+					Description: "What to show: overview, champions, roles, queues or best/worst games (default: overview)",
+					Required:    false,
+					Choices:     statsViewChoices,
+				},
+			},
+		},
+		Handler:      handleStatsCommand,
+		Autocomplete: autocompleteTrackedPlayers,
+		GuildOnly:    true,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "leaderboard",
+			Description: "Rank the players tracked in this server",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "metric",
+					Description: "What to rank players by",
+					Required:    true,
+					Choices:     leaderboardMetricChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "days",
+					Description: "Number of days to look back (default: 7)",
+					MinValue:    &minLeaderboardValue,
+					MaxValue:    maxLeaderboardDays,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "queue",
+					Description: "Only count games from this queue (default: all, Ranked Solo/Duo for LP)",
+					Choices:     queueFilterChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "min_games",
+					Description: "Leave out players with fewer games (default: 5)",
+					MinValue:    &minLeaderboardValue,
+					MaxValue:    maxLeaderboardMinGames,
+				},
+			},
+		},
+		Handler:   handleLeaderboardCommand,
+		GuildOnly: true,
+		Public:    true,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:        "tracked",
+			Description: "List all tracked players",
+		},
+		Handler:   handleTrackedCommand,
+		GuildOnly: true,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:                     "config",
+			Description:              "Configure the bot for this server",
+			DefaultMemberPermissions: &manageGuildPermissions,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "channel",
+					Description: "Set the channel game summaries are posted in",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "channel",
+							Description:  "Notification channel",
+							Required:     true,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "show",
+					Description: "Show this server's settings",
+				},
+			},
+		},
+		Handler:    handleConfigCommand,
+		GuildOnly:  true,
+		Permission: canConfigureGuild,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:                     "admin",
+			Description:              "Bot administration",
+			DefaultMemberPermissions: &adminPermissions,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "setkey",
+					Description: "Replace the Riot API key without restarting",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "key",
+							Description: "New Riot API key (RGAPI-...)",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "expires_in_hours",
+							Description: "Hours until the key expires (default: 24, 0 for production keys)",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "failedmatches",
+					Description: "List matches that failed processing too many times",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "retrymatch",
+					Description: "Queue a failed match for another attempt",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "match_id",
+							Description: "Match ID (e.g., NA1_1234567890)",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		Handler:    handleAdminCommand,
+		Permission: isBotAdmin,
+	},
+	{
+		ApplicationCommand: &discordgo.ApplicationCommand{
+			Name:                     "backfill",
+			Description:              "Import a tracked player's past games",
+			DefaultMemberPermissions: &adminPermissions,
+			Options: append([]*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "summoner",
+					Description:  "Summoner name (e.g., PlayerName#TAG)",
+					Required:     true,
+					Autocomplete: true,
+				},
+			}, backfillLimitOptions("",
+				"Import games from this many past days (default: 30)",
+				"Import at most this many games (default: 500)")...),
+		},
+		Handler:      handleBackfillCommand,
+		Autocomplete: autocompleteTrackedPlayers,
+		GuildOnly:    true,
+		Permission:   isGuildAdmin,
+		Defer:        true,
+	},
+}
+
+// router dispatches interactions to the registry. Middleware run outermost
+// first.
+var router = NewRouter(commands,
+	instrument,
+	respondErrors,
+	recoverPanics,
+	checkPermissions,
+	deferSlow,
+)
+
+// dmPermission is false for GuildOnly commands.
+var dmPermission = false
+
+func commandDefinitions() []*discordgo.ApplicationCommand {
+	definitions := make([]*discordgo.ApplicationCommand, 0, len(commands))
+	for _, command := range commands {
+		if command.GuildOnly {
+			command.DMPermission = &dmPermission
+		}
+		definitions = append(definitions, command.ApplicationCommand)
+	}
+	return definitions
+}
+
+// syncCommands makes Discord's command list match the registry. Bulk
+// overwrite is idempotent, so restarts never create duplicates, and commands
+// dropped from the registry are removed. With devGuildID set, the commands are
+// only registered in that guild, where changes show up immediately, and the
+// global commands are left untouched.
+func syncCommands(s *discordgo.Session, devGuildID string) error {
+	appID := s.State.User.ID
+	definitions := commandDefinitions()
+
+	if devGuildID != "" {
+		registered, err := s.ApplicationCommandBulkOverwrite(appID, devGuildID, definitions)
+		if err != nil {
+			return fmt.Errorf("registering commands in dev guild %s: %w", devGuildID, err)
+		}
+		log.Printf("Registered %d slash commands in dev guild %s", len(registered), devGuildID)
+		return nil
+	}
+
+	registered, err := s.ApplicationCommandBulkOverwrite(appID, "", definitions)
+	if err != nil {
+		return fmt.Errorf("registering global commands: %w", err)
+	}
+	log.Printf("Registered %d global slash commands", len(registered))
+
+	// Earlier versions also registered every command in each guild, which
+	// shows up as duplicates next to the global ones.
+	for _, guild := range s.State.Guilds {
+		removeGuildCommands(s, appID, guild.ID)
+	}
+	return nil
+}
+
+// removeGuildCommands deletes any guild-specific commands in guildID.
+func removeGuildCommands(s *discordgo.Session, appID, guildID string) {
+	existing, err := s.ApplicationCommands(appID, guildID)
+	if err != nil {
+		log.Printf("Error listing commands in guild %s: %v", guildID, err)
+		return
+	}
+	if len(existing) == 0 {
+		return
+	}
+
+	if _, err := s.ApplicationCommandBulkOverwrite(appID, guildID, []*discordgo.ApplicationCommand{}); err != nil {
+		log.Printf("Error removing stale commands in guild %s: %v", guildID, err)
+		return
+	}
+	log.Printf("Removed %d stale guild commands in guild %s", len(existing), guildID)
+}
+
+func handleHelpCommand(c *CommandContext) error {
+	helpText := `**League of Legends Bot Commands:**
+
+🎮 **Player Tracking:**
+• /track <summoner> [region] [backfill_days] [backfill_games] - Track a player's games (e.g., /track PlayerName#TAG region:EUW1), optionally importing their past games too
+• /untrack <summoner> - Stop tracking a player
+• /stats <summoner> [days] [queue] [view] - Show player stats (default: 7 days), or per champion, role, queue or best/worst games
+• /tracked - List the players tracked in this server
+• /leaderboard <metric> [days] [queue] [min_games] - Rank this server's players by win rate, KDA, games, CS/min, LP gained or vision/min
+
+⚙️ **Server Settings:**
+• /config channel <channel> - Choose where game summaries are posted
+• /config show - Show this server's settings
+
+📋 **Other Commands:**
+• /pn or /patchnotes - Get latest patch notes
+
+The bot will automatically post game summaries when tracked players finish games!`
+
+	return c.Respond(helpText)
+}
+
+func handlePatchNotesCommand(c *CommandContext) error {
+	return c.Respond(fmt.Sprintf("📋 **Latest League of Legends Patch Notes:**\n%s", getLatestPatchNotesURL()))
+}
@@ -1,18 +1,49 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// Database implements Store on PostgreSQL or SQLite. Queries stick to SQL
+// both understand ($N placeholders, ON CONFLICT, LIMIT); the dialect covers
+// the rest.
 type Database struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
 }
 
-func NewDatabase(host, port, user, password, dbname string) (*Database, error) {
+// dialect holds what differs between the databases a Database can run on.
+type dialect struct {
+	// migrations is the directory in migrationFiles with this database's
+	// schema.
+	migrations string
+	// lockMigrations keeps other processes from migrating until unlock is
+	// called; nil if the database needs no lock.
+	lockMigrations func(ctx context.Context, conn *sql.Conn) (unlock func(), err error)
+	// bindArgs converts query arguments before they reach the driver; nil
+	// to pass them as is.
+	bindArgs func(args []interface{}) []interface{}
+	// encodeJSON and decodeJSON convert a JSON document to and from the
+	// value stored in a payload column.
+	encodeJSON func(doc []byte) (interface{}, error)
+	decodeJSON func(stored []byte) ([]byte, error)
+}
+
+var postgresDialect = dialect{
+	migrations:     "migrations/postgres",
+	lockMigrations: postgresAdvisoryLock,
+	// JSONB columns take the document as text and compress it themselves
+	encodeJSON: func(doc []byte) (interface{}, error) { return string(doc), nil },
+	decodeJSON: func(stored []byte) ([]byte, error) { return stored, nil },
+}
+
+func NewPostgresStore(host, port, user, password, dbname string) (*Database, error) {
 	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		host, port, user, password, dbname)
 
@@ -25,33 +56,140 @@ func NewDatabase(host, port, user, password, dbname string) (*Database, error) {
 		return nil, err
 	}
 
-	database := &Database{db: db}
-	if err := initDB(db); err != nil {
+	return &Database{db: db, dialect: postgresDialect}, nil
+}
+
+// postgresAdvisoryLock holds migrationLockID for the session, so replicas
+// starting together do not apply the same migration twice.
+func postgresAdvisoryLock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
 		return nil, err
 	}
-
-	return database, nil
+	return func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}, nil
 }
 
 func (d *Database) Close() error {
 	return d.db.Close()
 }
 
+func (d *Database) bind(args []interface{}) []interface{} {
+	if d.dialect.bindArgs == nil {
+		return args
+	}
+	return d.dialect.bindArgs(args)
+}
+
+func (d *Database) exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.db.Exec(query, d.bind(args)...)
+}
+
+func (d *Database) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.Query(query, d.bind(args)...)
+}
+
+func (d *Database) queryRow(query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRow(query, d.bind(args)...)
+}
+
 func (d *Database) AddTrackedPlayer(player *TrackedPlayer) error {
 	query := `
-		INSERT INTO tracked_players (puuid, game_name, tag_line, summoner_id, last_match_id, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO tracked_players (puuid, game_name, tag_line, summoner_id, platform, last_match_id, last_match_at,
+			account_checked_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
 		ON CONFLICT (puuid) DO UPDATE SET
-			game_name = $2, tag_line = $3, summoner_id = $4, last_match_id = $5, updated_at = $6`
+			game_name = $2, tag_line = $3, summoner_id = $4, platform = $5, last_match_id = $6, last_match_at = $7,
+			account_checked_at = $8, updated_at = $8`
 
-	_, err := d.db.Exec(query, player.PUUID, player.GameName, player.TagLine, player.SummonerID, player.LastMatchID, time.Now())
+	_, err := d.exec(query, player.PUUID, player.GameName, player.TagLine, player.SummonerID, player.Platform,
+		player.LastMatchID, nullTime(player.LastMatchAt), time.Now())
 	return err
 }
 
 func (d *Database) GetTrackedPlayers() ([]TrackedPlayer, error) {
-	query := `SELECT id, puuid, game_name, tag_line, summoner_id, last_match_id, created_at, updated_at FROM tracked_players`
+	query := `SELECT ` + trackedPlayerColumns + ` FROM tracked_players`
+
+	rows, err := d.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []TrackedPlayer
+	for rows.Next() {
+		player, err := scanTrackedPlayer(rows)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, *player)
+	}
+
+	return players, nil
+}
+
+func (d *Database) GetTrackedPlayer(puuid string) (*TrackedPlayer, error) {
+	query := `SELECT ` + trackedPlayerColumns + ` FROM tracked_players WHERE puuid = $1`
+
+	player, err := scanTrackedPlayer(d.queryRow(query, puuid))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return player, err
+}
+
+// GetGuildPlayers returns the players tracked in guildID.
+func (d *Database) GetGuildPlayers(guildID string) ([]TrackedPlayer, error) {
+	query := `SELECT ` + prefixColumns("tp.", trackedPlayerColumns) + `
+		FROM tracked_players tp
+		JOIN guild_players gp ON gp.puuid = tp.puuid
+		WHERE gp.guild_id = $1
+		ORDER BY tp.game_name, tp.tag_line`
+
+	rows, err := d.query(query, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []TrackedPlayer
+	for rows.Next() {
+		player, err := scanTrackedPlayer(rows)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, *player)
+	}
 
-	rows, err := d.db.Query(query)
+	return players, nil
+}
+
+// SearchGuildPlayers finds players tracked in guildID whose "Name#TAG"
+// matches query case-insensitively: prefix matches first, then substring
+// matches, then names containing query's characters in order (so "fkr"
+// finds "Faker#KR1").
+func (d *Database) SearchGuildPlayers(guildID, query string, limit int) ([]TrackedPlayer, error) {
+	query = strings.TrimSpace(query)
+	var fuzzy strings.Builder
+	fuzzy.WriteString("%")
+	for _, r := range query {
+		fuzzy.WriteString(escapeLike(string(r)))
+		fuzzy.WriteString("%")
+	}
+	literal := escapeLike(query)
+
+	sqlQuery := `SELECT ` + prefixColumns("tp.", trackedPlayerColumns) + `
+		FROM tracked_players tp
+		JOIN guild_players gp ON gp.puuid = tp.puuid
+		WHERE gp.guild_id = $1 AND LOWER(tp.game_name || '#' || tp.tag_line) LIKE LOWER($4) ESCAPE '\'
+		ORDER BY CASE
+				WHEN LOWER(tp.game_name || '#' || tp.tag_line) LIKE LOWER($2) ESCAPE '\' THEN 0
+				WHEN LOWER(tp.game_name || '#' || tp.tag_line) LIKE LOWER($3) ESCAPE '\' THEN 1
+				ELSE 2
+			END, tp.game_name, tp.tag_line
+		LIMIT $5`
+
+	rows, err := d.query(sqlQuery, guildID, literal+"%", "%"+literal+"%", fuzzy.String(), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -59,55 +197,264 @@ func (d *Database) GetTrackedPlayers() ([]TrackedPlayer, error) {
 
 	var players []TrackedPlayer
 	for rows.Next() {
-		var player TrackedPlayer
-		err := rows.Scan(&player.ID, &player.PUUID, &player.GameName, &player.TagLine,
-			&player.SummonerID, &player.LastMatchID, &player.CreatedAt, &player.UpdatedAt)
+		player, err := scanTrackedPlayer(rows)
 		if err != nil {
 			return nil, err
 		}
-		players = append(players, player)
+		players = append(players, *player)
 	}
 
 	return players, nil
 }
 
-func (d *Database) RemoveTrackedPlayer(puuid string) error {
-	query := `DELETE FROM tracked_players WHERE puuid = $1`
-	_, err := d.db.Exec(query, puuid)
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// AddGuildPlayer subscribes guildID to an already tracked player.
+func (d *Database) AddGuildPlayer(guildID, puuid, addedBy string) error {
+	query := `
+		INSERT INTO guild_players (guild_id, puuid, added_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (guild_id, puuid) DO NOTHING`
+	_, err := d.exec(query, guildID, puuid, addedBy)
 	return err
 }
 
-func (d *Database) UpdateLastMatchID(puuid, matchID string) error {
-	query := `UPDATE tracked_players SET last_match_id = $1, updated_at = $2 WHERE puuid = $3`
-	_, err := d.db.Exec(query, matchID, time.Now(), puuid)
+// RemoveGuildPlayer unsubscribes guildID from puuid and stops tracking the
+// player altogether once no guild is left. It reports whether guildID was
+// subscribed.
+func (d *Database) RemoveGuildPlayer(guildID, puuid string) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM guild_players WHERE guild_id = $1 AND puuid = $2`, guildID, puuid)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM tracked_players
+		WHERE puuid = $1 AND NOT EXISTS (SELECT 1 FROM guild_players WHERE puuid = $1)`, puuid)
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, tx.Commit()
+}
+
+// AdoptUnscopedPlayers subscribes guildID to every tracked player that no
+// guild tracks yet, i.e. players added before tracking was per guild.
+func (d *Database) AdoptUnscopedPlayers(guildID string) (int64, error) {
+	result, err := d.exec(`
+		INSERT INTO guild_players (guild_id, puuid)
+		SELECT $1, puuid FROM tracked_players tp
+		WHERE NOT EXISTS (SELECT 1 FROM guild_players gp WHERE gp.puuid = tp.puuid)`, guildID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetPlayerChannels returns the notification channels of every guild that
+// tracks puuid.
+func (d *Database) GetPlayerChannels(puuid string) ([]string, error) {
+	query := `
+		SELECT DISTINCT gs.channel_id
+		FROM guild_players gp
+		JOIN guild_settings gs ON gs.guild_id = gp.guild_id
+		WHERE gp.puuid = $1 AND gs.channel_id <> ''`
+
+	rows, err := d.query(query, puuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []string
+	for rows.Next() {
+		var channelID string
+		if err := rows.Scan(&channelID); err != nil {
+			return nil, err
+		}
+		channels = append(channels, channelID)
+	}
+	return channels, nil
+}
+
+// GetGuildSettings returns guildID's settings, or nil if it has none yet.
+func (d *Database) GetGuildSettings(guildID string) (*GuildSettings, error) {
+	query := `SELECT guild_id, channel_id, created_at, updated_at FROM guild_settings WHERE guild_id = $1`
+
+	var settings GuildSettings
+	err := d.queryRow(query, guildID).Scan(&settings.GuildID, &settings.ChannelID, &settings.CreatedAt, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (d *Database) SetGuildChannel(guildID, channelID string) error {
+	query := `
+		INSERT INTO guild_settings (guild_id, channel_id, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (guild_id) DO UPDATE SET channel_id = $2, updated_at = $3`
+	_, err := d.exec(query, guildID, channelID, time.Now())
+	return err
+}
+
+// UpdateLastMatchID moves a player's cursor to matchID. matchTime is the
+// match's creation time; pass the zero time to keep the stored one.
+func (d *Database) UpdateLastMatchID(puuid, matchID string, matchTime time.Time) error {
+	query := `UPDATE tracked_players SET last_match_id = $1, last_match_at = COALESCE($2, last_match_at), updated_at = $3 WHERE puuid = $4`
+	_, err := d.exec(query, matchID, nullTime(matchTime), time.Now(), puuid)
+	return err
+}
+
+// UpdatePollSchedule stores a player's poll state and when to check them next.
+func (d *Database) UpdatePollSchedule(puuid, state string, nextCheckAt time.Time) error {
+	query := `UPDATE tracked_players SET poll_state = $1, next_check_at = $2 WHERE puuid = $3`
+	_, err := d.exec(query, state, nextCheckAt, puuid)
 	return err
 }
 
+// GetLastGameTime returns the start of the most recent stored match for
+// puuid, or the zero time if there is none.
+func (d *Database) GetLastGameTime(puuid string) (time.Time, error) {
+	var last time.Time
+	err := d.queryRow(`SELECT game_creation FROM match_data WHERE puuid = $1 ORDER BY game_creation DESC LIMIT 1`,
+		puuid).Scan(&last)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return last, err
+}
+
 func (d *Database) AddMatchData(match *MatchData) error {
 	query := `
 		INSERT INTO match_data 
-		(match_id, puuid, champion, game_mode, game_duration, win, kills, deaths, assists, 
-		 creep_score, damage_dealt, damage_taken, vision_score, gold_earned, items, game_creation)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		(match_id, puuid, champion, game_mode, queue_id, game_duration, win, kills, deaths, assists, 
+		 creep_score, damage_dealt, damage_taken, vision_score, gold_earned, items, game_creation, team_position,
+		 team_kills, cs_per_minute, damage_per_minute, gold_per_minute, vision_per_minute, kill_participation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+		        $19, $20, $21, $22, $23, $24)
 		ON CONFLICT (match_id, puuid) DO NOTHING`
 
-	_, err := d.db.Exec(query, match.MatchID, match.PUUID, match.Champion, match.GameMode,
+	_, err := d.exec(query, match.MatchID, match.PUUID, match.Champion, match.GameMode, match.QueueID,
 		match.GameDuration, match.Win, match.Kills, match.Deaths, match.Assists,
 		match.CreepScore, match.DamageDealt, match.DamageTaken, match.VisionScore,
-		match.GoldEarned, match.Items, match.GameCreation)
+		match.GoldEarned, match.Items, match.GameCreation, match.TeamPosition,
+		match.TeamKills, match.CSPerMinute, match.DamagePerMinute, match.GoldPerMinute,
+		match.VisionPerMinute, match.KillParticipation)
 	return err
 }
 
-func (d *Database) GetPlayerStats(puuid string, days int) ([]MatchData, error) {
+// statsConditions is the WHERE clause selecting filter's matches from
+// match_data, with its arguments.
+func statsConditions(filter StatsFilter) (string, []interface{}) {
+	args := []interface{}{filter.Since}
+	conditions := []string{"game_creation >= $1"}
+	if len(filter.PUUIDs) > 0 {
+		conditions = append(conditions, "puuid IN ("+placeholders(len(args)+1, len(filter.PUUIDs))+")")
+		for _, puuid := range filter.PUUIDs {
+			args = append(args, puuid)
+		}
+	}
+	if len(filter.QueueIDs) > 0 {
+		conditions = append(conditions, "queue_id IN ("+placeholders(len(args)+1, len(filter.QueueIDs))+")")
+		for _, id := range filter.QueueIDs {
+			args = append(args, id)
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetStatGroups totals filter's matches per grouping, most played first.
+// StatsTotal gives a single group, with no games if nothing matched. A
+// positive limit keeps only that many groups.
+func (d *Database) GetStatGroups(filter StatsFilter, grouping StatsGrouping, limit int) ([]StatGroup, error) {
+	// Queue IDs were not always stored, so queues are told apart by game
+	// mode too
+	key, groupBy := "''", ""
+	switch grouping {
+	case GroupByChampion, GroupByRole, GroupByPlayer:
+		key, groupBy = string(grouping), "GROUP BY "+string(grouping)
+	case GroupByQueue:
+		key, groupBy = "queue_id", "GROUP BY queue_id, game_mode"
+	}
+
+	where, args := statsConditions(filter)
+	query := `
+		SELECT ` + key + `, MIN(game_mode), COUNT(*), COALESCE(SUM(CASE WHEN win THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(kills), 0), COALESCE(SUM(deaths), 0), COALESCE(SUM(assists), 0),
+		       COALESCE(SUM(creep_score), 0), COALESCE(SUM(damage_dealt), 0), COALESCE(SUM(gold_earned), 0),
+		       COALESCE(SUM(vision_score), 0), COALESCE(AVG(cs_per_minute), 0), COALESCE(AVG(damage_per_minute), 0),
+		       COALESCE(AVG(gold_per_minute), 0), COALESCE(AVG(vision_per_minute), 0),
+		       COALESCE(AVG(CASE WHEN team_kills > 0 THEN kill_participation END), 0),
+		       COALESCE(SUM(CASE WHEN team_kills > 0 THEN 1 ELSE 0 END), 0)
+		FROM match_data
+		WHERE ` + where + `
+		` + groupBy + `
+		ORDER BY COUNT(*) DESC, 1`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []StatGroup
+	for rows.Next() {
+		var group StatGroup
+		var gameMode sql.NullString
+		err := rows.Scan(&group.Key, &gameMode, &group.Games, &group.Wins, &group.Kills, &group.Deaths,
+			&group.Assists, &group.CreepScore, &group.DamageDealt, &group.GoldEarned, &group.VisionScore,
+			&group.CSPerMinute, &group.DamagePerMinute, &group.GoldPerMinute, &group.VisionPerMinute,
+			&group.KillParticipation, &group.KillParticipationGames)
+		if err != nil {
+			return nil, err
+		}
+		group.GameMode = gameMode.String
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// GetGamesByPerformance returns filter's limit best matches by KDA ratio,
+// or the worst ones if best is false. Wins rank above losses on a tie.
+func (d *Database) GetGamesByPerformance(filter StatsFilter, best bool, limit int) ([]MatchData, error) {
+	order := "DESC"
+	if !best {
+		order = "ASC"
+	}
+
+	where, args := statsConditions(filter)
 	query := `
-		SELECT match_id, puuid, champion, game_mode, game_duration, win, kills, deaths, assists,
-		       creep_score, damage_dealt, damage_taken, vision_score, gold_earned, items, game_creation, extracted_at
-		FROM match_data 
-		WHERE puuid = $1 AND game_creation >= NOW() - INTERVAL '%d days'
-		ORDER BY game_creation DESC`
+		SELECT match_id, puuid, champion, team_position, game_mode, queue_id, game_duration, win,
+		       kills, deaths, assists, creep_score, damage_dealt, damage_taken, vision_score, gold_earned,
+		       items, game_creation, extracted_at, team_kills, cs_per_minute, damage_per_minute, gold_per_minute,
+		       vision_per_minute, kill_participation
+		FROM match_data
+		WHERE ` + where + `
+		ORDER BY (kills + assists) * 1.0 / (CASE WHEN deaths > 0 THEN deaths ELSE 1 END) ` + order + `,
+		         (CASE WHEN win THEN 1 ELSE 0 END) ` + order + `, game_creation DESC
+		LIMIT ` + fmt.Sprintf("%d", limit)
 
-	formattedQuery := fmt.Sprintf(query, days)
-	rows, err := d.db.Query(formattedQuery, puuid)
+	rows, err := d.query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -116,31 +463,551 @@ func (d *Database) GetPlayerStats(puuid string, days int) ([]MatchData, error) {
 	var matches []MatchData
 	for rows.Next() {
 		var match MatchData
-		err := rows.Scan(&match.MatchID, &match.PUUID, &match.Champion, &match.GameMode,
-			&match.GameDuration, &match.Win, &match.Kills, &match.Deaths, &match.Assists,
+		err := rows.Scan(&match.MatchID, &match.PUUID, &match.Champion, &match.TeamPosition, &match.GameMode,
+			&match.QueueID, &match.GameDuration, &match.Win, &match.Kills, &match.Deaths, &match.Assists,
 			&match.CreepScore, &match.DamageDealt, &match.DamageTaken, &match.VisionScore,
-			&match.GoldEarned, &match.Items, &match.GameCreation, &match.ExtractedAt)
+			&match.GoldEarned, &match.Items, &match.GameCreation, &match.ExtractedAt, &match.TeamKills,
+			&match.CSPerMinute, &match.DamagePerMinute, &match.GoldPerMinute, &match.VisionPerMinute,
+			&match.KillParticipation)
 		if err != nil {
 			return nil, err
 		}
 		matches = append(matches, match)
 	}
+	return matches, rows.Err()
+}
+
+// UpdateMatchData rewrites the stored row for match's player from freshly
+// derived values. It does nothing if there is no such row.
+func (d *Database) UpdateMatchData(match *MatchData) error {
+	query := `
+		UPDATE match_data SET champion = $3, game_mode = $4, queue_id = $5, game_duration = $6, win = $7,
+			kills = $8, deaths = $9, assists = $10, creep_score = $11, damage_dealt = $12, damage_taken = $13,
+			vision_score = $14, gold_earned = $15, items = $16, game_creation = $17, team_position = $18,
+			team_kills = $19, cs_per_minute = $20, damage_per_minute = $21, gold_per_minute = $22,
+			vision_per_minute = $23, kill_participation = $24
+		WHERE match_id = $1 AND puuid = $2`
 
-	return matches, nil
+	_, err := d.exec(query, match.MatchID, match.PUUID, match.Champion, match.GameMode, match.QueueID,
+		match.GameDuration, match.Win, match.Kills, match.Deaths, match.Assists,
+		match.CreepScore, match.DamageDealt, match.DamageTaken, match.VisionScore,
+		match.GoldEarned, match.Items, match.GameCreation, match.TeamPosition,
+		match.TeamKills, match.CSPerMinute, match.DamagePerMinute, match.GoldPerMinute,
+		match.VisionPerMinute, match.KillParticipation)
+	return err
 }
 
-func (d *Database) GetPlayerByRiotID(gameName, tagLine string) (*TrackedPlayer, error) {
-	query := `SELECT id, puuid, game_name, tag_line, summoner_id, last_match_id, created_at, updated_at 
-			  FROM tracked_players WHERE game_name = $1 AND tag_line = $2`
+// SaveMatch stores match's raw payload and a row for every participant,
+// replacing whatever was stored for the match before.
+func (d *Database) SaveMatch(match *Match) error {
+	payload, err := d.dialect.encodeJSON(match.Raw)
+	if err != nil {
+		return fmt.Errorf("encoding payload: %w", err)
+	}
 
-	var player TrackedPlayer
-	err := d.db.QueryRow(query, gameName, tagLine).Scan(
-		&player.ID, &player.PUUID, &player.GameName, &player.TagLine,
-		&player.SummonerID, &player.LastMatchID, &player.CreatedAt, &player.UpdatedAt)
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO matches (match_id, game_id, platform, queue_id, game_mode, game_duration, game_creation, payload, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (match_id) DO UPDATE SET
+			game_id = $2, platform = $3, queue_id = $4, game_mode = $5, game_duration = $6, game_creation = $7,
+			payload = $8, fetched_at = $9`,
+		d.bind([]interface{}{match.Metadata.MatchID, fmt.Sprintf("%d", match.Info.GameID), match.Info.PlatformID,
+			match.Info.QueueID, match.Info.GameMode, match.Info.GameDuration, match.CreatedAt(), payload, time.Now()})...)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO match_participants (match_id, puuid, participant_id, team_id, team_position, riot_id_game_name,
+			riot_id_tagline, champion_id, champion, win, kills, deaths, assists, creep_score, damage_dealt,
+			damage_taken, vision_score, gold_earned, items)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		ON CONFLICT (match_id, puuid) DO UPDATE SET
+			participant_id = $3, team_id = $4, team_position = $5, riot_id_game_name = $6, riot_id_tagline = $7,
+			champion_id = $8, champion = $9, win = $10, kills = $11, deaths = $12, assists = $13, creep_score = $14,
+			damage_dealt = $15, damage_taken = $16, vision_score = $17, gold_earned = $18, items = $19`
+	for _, p := range match.Info.Participants {
+		_, err := tx.Exec(query, match.Metadata.MatchID, p.PUUID, p.ParticipantID, p.TeamID, p.TeamPosition,
+			p.RiotIDGameName, p.RiotIDTagline, p.ChampionID, p.ChampionName, p.Win, p.Kills, p.Deaths, p.Assists,
+			p.TotalMinionsKilled, p.TotalDamageDealt, p.TotalDamageTaken, p.VisionScore, p.GoldEarned, p.itemsJSON())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetMatch returns the stored match with ID matchID (e.g. "EUW1_123"), or
+// nil if it was never stored.
+func (d *Database) GetMatch(matchID string) (*Match, error) {
+	var stored []byte
+	err := d.queryRow(`SELECT payload FROM matches WHERE match_id = $1`, matchID).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := d.dialect.decodeJSON(stored)
+	if err != nil {
+		return nil, fmt.Errorf("decoding payload of %s: %w", matchID, err)
+	}
+	return parseMatch(payload)
+}
+
+// GetStoredMatchIDs returns the IDs of every stored match, oldest first.
+func (d *Database) GetStoredMatchIDs() ([]string, error) {
+	rows, err := d.query(`SELECT match_id FROM matches ORDER BY game_creation, match_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matchIDs []string
+	for rows.Next() {
+		var matchID string
+		if err := rows.Scan(&matchID); err != nil {
+			return nil, err
+		}
+		matchIDs = append(matchIDs, matchID)
+	}
+	return matchIDs, rows.Err()
+}
+
+// GetGuildPlayerByRiotID looks up a player tracked in guildID by their
+// current or a previous Riot ID. Riot IDs are case-insensitive.
+func (d *Database) GetGuildPlayerByRiotID(guildID, gameName, tagLine string) (*TrackedPlayer, error) {
+	query := `SELECT ` + prefixColumns("tp.", trackedPlayerColumns) + `
+			  FROM tracked_players tp
+			  JOIN guild_players gp ON gp.puuid = tp.puuid
+			  WHERE gp.guild_id = $1 AND (
+			        (LOWER(tp.game_name) = LOWER($2) AND LOWER(tp.tag_line) = LOWER($3))
+			        OR EXISTS (
+			            SELECT 1 FROM player_name_history h
+			            WHERE h.puuid = tp.puuid AND LOWER(h.game_name) = LOWER($2) AND LOWER(h.tag_line) = LOWER($3)))
+			  ORDER BY (LOWER(tp.game_name) = LOWER($2) AND LOWER(tp.tag_line) = LOWER($3)) DESC
+			  LIMIT 1`
+
+	return scanTrackedPlayer(d.queryRow(query, guildID, gameName, tagLine))
+}
+
+const trackedPlayerColumns = `id, puuid, game_name, tag_line, summoner_id, platform, last_match_id, last_match_at,
+	poll_state, next_check_at, account_checked_at, created_at, updated_at`
+
+// prefixColumns qualifies each column in a column list with prefix, for
+// queries that join another table.
+func prefixColumns(prefix, columns string) string {
+	fields := strings.Split(columns, ",")
+	for idx, field := range fields {
+		fields[idx] = prefix + strings.TrimSpace(field)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// placeholders returns n comma-separated placeholders starting at $first,
+// for IN lists.
+func placeholders(first, n int) string {
+	list := make([]string, n)
+	for idx := range list {
+		list[idx] = fmt.Sprintf("$%d", first+idx)
+	}
+	return strings.Join(list, ", ")
+}
 
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTrackedPlayer(row rowScanner) (*TrackedPlayer, error) {
+	var player TrackedPlayer
+	var lastMatchAt, nextCheckAt, accountCheckedAt sql.NullTime
+	err := row.Scan(&player.ID, &player.PUUID, &player.GameName, &player.TagLine,
+		&player.SummonerID, &player.Platform, &player.LastMatchID, &lastMatchAt,
+		&player.PollState, &nextCheckAt, &accountCheckedAt, &player.CreatedAt, &player.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	player.LastMatchAt = lastMatchAt.Time
+	player.NextCheckAt = nextCheckAt.Time
+	player.AccountCheckedAt = accountCheckedAt.Time
 
 	return &player, nil
 }
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+const queuedMatchColumns = `id, puuid, match_id, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+func scanQueuedMatch(row rowScanner) (*QueuedMatch, error) {
+	var entry QueuedMatch
+	err := row.Scan(&entry.ID, &entry.PUUID, &entry.MatchID, &entry.Status, &entry.Attempts,
+		&entry.LastError, &entry.NextAttemptAt, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// EnqueueMatch records matchID as pending for puuid unless it is already
+// queued, and returns the queue entry. inserted is true for new entries.
+func (d *Database) EnqueueMatch(puuid, matchID string) (entry *QueuedMatch, inserted bool, err error) {
+	query := `
+		INSERT INTO match_queue (puuid, match_id, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (puuid, match_id) DO NOTHING`
+
+	result, err := d.exec(query, puuid, matchID, MatchStatusPending, time.Now())
+	if err != nil {
+		return nil, false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry, err = scanQueuedMatch(d.queryRow(
+		`SELECT `+queuedMatchColumns+` FROM match_queue WHERE puuid = $1 AND match_id = $2`, puuid, matchID))
+	if err != nil {
+		return nil, false, err
+	}
+	return entry, affected > 0, nil
+}
+
+// GetPendingMatches returns a player's pending matches that are due for an
+// attempt, oldest first.
+func (d *Database) GetPendingMatches(puuid string) ([]QueuedMatch, error) {
+	query := `SELECT ` + queuedMatchColumns + ` FROM match_queue
+			  WHERE puuid = $1 AND status = $2 AND next_attempt_at <= $3
+			  ORDER BY id`
+
+	rows, err := d.query(query, puuid, MatchStatusPending, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []QueuedMatch
+	for rows.Next() {
+		entry, err := scanQueuedMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (d *Database) MarkMatchProcessed(puuid, matchID string) error {
+	query := `UPDATE match_queue SET status = $1, last_error = '', updated_at = $2 WHERE puuid = $3 AND match_id = $4`
+	_, err := d.exec(query, MatchStatusProcessed, time.Now(), puuid, matchID)
+	return err
+}
+
+// RecordMatchFailure stores a failed attempt. The entry stays pending until
+// nextAttemptAt, or moves to failed when status is MatchStatusFailed.
+func (d *Database) RecordMatchFailure(puuid, matchID, status, lastError string, attempts int, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE match_queue SET status = $1, attempts = $2, last_error = $3, next_attempt_at = $4, updated_at = $5
+		WHERE puuid = $6 AND match_id = $7`
+	_, err := d.exec(query, status, attempts, lastError, nextAttemptAt, time.Now(), puuid, matchID)
+	return err
+}
+
+// GetFailedMatches returns matches that gave up after repeated failures,
+// most recent first.
+func (d *Database) GetFailedMatches(limit int) ([]QueuedMatch, error) {
+	query := `SELECT ` + queuedMatchColumns + ` FROM match_queue WHERE status = $1 ORDER BY updated_at DESC LIMIT $2`
+
+	rows, err := d.query(query, MatchStatusFailed, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []QueuedMatch
+	for rows.Next() {
+		entry, err := scanQueuedMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// RetryFailedMatch puts a failed match back into the queue with a fresh
+// attempt budget. It returns the number of entries reset.
+func (d *Database) RetryFailedMatch(matchID string) (int64, error) {
+	query := `
+		UPDATE match_queue SET status = $1, attempts = 0, next_attempt_at = $2, updated_at = $2
+		WHERE match_id = $3 AND status = $4`
+	result, err := d.exec(query, MatchStatusPending, time.Now(), matchID, MatchStatusFailed)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (d *Database) AddLiveGame(game *LiveGame) error {
+	query := `
+		INSERT INTO live_games (puuid, game_id, channel_id, message_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (puuid, game_id, channel_id) DO NOTHING`
+	_, err := d.exec(query, game.PUUID, game.GameID, game.ChannelID, game.MessageID)
+	return err
+}
+
+// GetLiveGames returns the live messages posted for puuid's game, one per
+// channel.
+func (d *Database) GetLiveGames(puuid, gameID string) ([]LiveGame, error) {
+	query := `SELECT puuid, game_id, channel_id, message_id, created_at FROM live_games WHERE puuid = $1 AND game_id = $2`
+
+	rows, err := d.query(query, puuid, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []LiveGame
+	for rows.Next() {
+		var game LiveGame
+		if err := rows.Scan(&game.PUUID, &game.GameID, &game.ChannelID, &game.MessageID, &game.CreatedAt); err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+func (d *Database) RemoveLiveGame(puuid, gameID string) error {
+	_, err := d.exec(`DELETE FROM live_games WHERE puuid = $1 AND game_id = $2`, puuid, gameID)
+	return err
+}
+
+// RemoveStaleLiveGames forgets live messages for games that never showed up
+// in match-v5 (e.g. custom games).
+func (d *Database) RemoveStaleLiveGames(olderThan time.Time) error {
+	_, err := d.exec(`DELETE FROM live_games WHERE created_at < $1`, olderThan)
+	return err
+}
+
+func (d *Database) AddRankSnapshot(snapshot *RankSnapshot) error {
+	query := `
+		INSERT INTO rank_snapshots (puuid, queue_type, tier, rank, league_points, wins, losses, match_id, lp_change, captured_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	var lpChange sql.NullInt64
+	if snapshot.LPChange != nil {
+		lpChange = sql.NullInt64{Int64: int64(*snapshot.LPChange), Valid: true}
+	}
+	_, err := d.exec(query, snapshot.PUUID, snapshot.QueueType, snapshot.Tier, snapshot.Rank,
+		snapshot.LeaguePoints, snapshot.Wins, snapshot.Losses, snapshot.MatchID, lpChange, snapshot.CapturedAt)
+	return err
+}
+
+// GetRankSnapshotBefore returns the latest snapshot for puuid in queueType
+// captured at or before t, or nil if there is none.
+func (d *Database) GetRankSnapshotBefore(puuid, queueType string, t time.Time) (*RankSnapshot, error) {
+	query := `
+		SELECT id, puuid, queue_type, tier, rank, league_points, wins, losses, match_id, lp_change, captured_at
+		FROM rank_snapshots
+		WHERE puuid = $1 AND queue_type = $2 AND captured_at <= $3
+		ORDER BY captured_at DESC, id DESC
+		LIMIT 1`
+
+	return scanRankSnapshot(d.queryRow(query, puuid, queueType, t))
+}
+
+// GetRankSnapshotAfter returns the first snapshot of puuid's standing in
+// queueType taken at or after t, or nil if there is none.
+func (d *Database) GetRankSnapshotAfter(puuid, queueType string, t time.Time) (*RankSnapshot, error) {
+	query := `
+		SELECT id, puuid, queue_type, tier, rank, league_points, wins, losses, match_id, lp_change, captured_at
+		FROM rank_snapshots
+		WHERE puuid = $1 AND queue_type = $2 AND captured_at >= $3
+		ORDER BY captured_at, id
+		LIMIT 1`
+
+	return scanRankSnapshot(d.queryRow(query, puuid, queueType, t))
+}
+
+func scanRankSnapshot(row rowScanner) (*RankSnapshot, error) {
+	var snapshot RankSnapshot
+	var lpChange sql.NullInt64
+	err := row.Scan(&snapshot.ID, &snapshot.PUUID, &snapshot.QueueType,
+		&snapshot.Tier, &snapshot.Rank, &snapshot.LeaguePoints, &snapshot.Wins, &snapshot.Losses,
+		&snapshot.MatchID, &lpChange, &snapshot.CapturedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lpChange.Valid {
+		change := int(lpChange.Int64)
+		snapshot.LPChange = &change
+	}
+	return &snapshot, nil
+}
+
+// RenamePlayer stores puuid's new Riot ID and records the old one in the
+// name history.
+func (d *Database) RenamePlayer(puuid, oldGameName, oldTagLine, gameName, tagLine string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO player_name_history (puuid, game_name, tag_line) VALUES ($1, $2, $3)`,
+		puuid, oldGameName, oldTagLine)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE tracked_players
+		SET game_name = $2, tag_line = $3, account_checked_at = $4, updated_at = $4
+		WHERE puuid = $1`, d.bind([]interface{}{puuid, gameName, tagLine, time.Now()})...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRiotID stores puuid's Riot ID without recording a rename, e.g. to
+// take Riot's capitalization of it.
+func (d *Database) UpdateRiotID(puuid, gameName, tagLine string, checkedAt time.Time) error {
+	_, err := d.exec(`
+		UPDATE tracked_players
+		SET game_name = $2, tag_line = $3, account_checked_at = $4, updated_at = $4
+		WHERE puuid = $1`, puuid, gameName, tagLine, checkedAt)
+	return err
+}
+
+func (d *Database) MarkAccountChecked(puuid string, checkedAt time.Time) error {
+	_, err := d.exec(`UPDATE tracked_players SET account_checked_at = $2 WHERE puuid = $1`, puuid, checkedAt)
+	return err
+}
+
+// GetNameHistory returns puuid's previous Riot IDs, most recent first.
+func (d *Database) GetNameHistory(puuid string) ([]NameChange, error) {
+	query := `
+		SELECT id, puuid, game_name, tag_line, changed_at
+		FROM player_name_history
+		WHERE puuid = $1
+		ORDER BY changed_at DESC, id DESC`
+
+	rows, err := d.query(query, puuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []NameChange
+	for rows.Next() {
+		var change NameChange
+		if err := rows.Scan(&change.ID, &change.PUUID, &change.GameName, &change.TagLine, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+const backfillJobColumns = `id, puuid, since, max_games, next_index, stored, status, last_error, app_id,
+	interaction_token, message_id, created_at, updated_at`
+
+func scanBackfillJob(row rowScanner) (*BackfillJob, error) {
+	var job BackfillJob
+	var since sql.NullTime
+	err := row.Scan(&job.ID, &job.PUUID, &since, &job.MaxGames, &job.NextIndex, &job.Stored, &job.Status,
+		&job.LastError, &job.AppID, &job.InteractionToken, &job.MessageID, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Since = since.Time
+	return &job, nil
+}
+
+// AddBackfillJob queues job and sets its ID.
+func (d *Database) AddBackfillJob(job *BackfillJob) error {
+	query := `
+		INSERT INTO backfill_jobs (puuid, since, max_games, status, app_id, interaction_token, message_id,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		RETURNING id`
+
+	now := time.Now()
+	err := d.queryRow(query, job.PUUID, nullTime(job.Since), job.MaxGames, BackfillStatusPending, job.AppID,
+		job.InteractionToken, job.MessageID, now).Scan(&job.ID)
+	if err != nil {
+		return err
+	}
+	job.Status = BackfillStatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	return nil
+}
+
+// GetActiveBackfill returns puuid's pending backfill, or nil if there is
+// none.
+func (d *Database) GetActiveBackfill(puuid string) (*BackfillJob, error) {
+	query := `SELECT ` + backfillJobColumns + ` FROM backfill_jobs WHERE puuid = $1 AND status = $2 ORDER BY id LIMIT 1`
+
+	job, err := scanBackfillJob(d.queryRow(query, puuid, BackfillStatusPending))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// GetPendingBackfills returns the unfinished backfills, oldest first.
+func (d *Database) GetPendingBackfills() ([]BackfillJob, error) {
+	query := `SELECT ` + backfillJobColumns + ` FROM backfill_jobs WHERE status = $1 ORDER BY id`
+
+	rows, err := d.query(query, BackfillStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []BackfillJob
+	for rows.Next() {
+		job, err := scanBackfillJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateBackfillProgress saves how far a backfill got, so it resumes there
+// after a restart.
+func (d *Database) UpdateBackfillProgress(id, nextIndex, stored int) error {
+	query := `UPDATE backfill_jobs SET next_index = $2, stored = $3, updated_at = $4 WHERE id = $1`
+	_, err := d.exec(query, id, nextIndex, stored, time.Now())
+	return err
+}
+
+// FinishBackfill marks a backfill done or failed.
+func (d *Database) FinishBackfill(id int, status, lastError string) error {
+	query := `UPDATE backfill_jobs SET status = $2, last_error = $3, updated_at = $4 WHERE id = $1`
+	_, err := d.exec(query, id, status, lastError, time.Now())
+	return err
+}
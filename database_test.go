@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMatchQueue(t *testing.T) {
+	store := newTestStore(t)
+	const puuid, matchID = "player", "NA1_1"
+
+	pendingIDs := func() []string {
+		t.Helper()
+		entries, err := store.GetPendingMatches(puuid)
+		if err != nil {
+			t.Fatalf("getting pending matches: %v", err)
+		}
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.MatchID)
+		}
+		return ids
+	}
+
+	entry, inserted, err := store.EnqueueMatch(puuid, matchID)
+	if err != nil || !inserted || entry.Status != MatchStatusPending {
+		t.Fatalf("first enqueue = %+v, %v, %v; want a new pending entry", entry, inserted, err)
+	}
+	if _, inserted, err := store.EnqueueMatch(puuid, matchID); err != nil || inserted {
+		t.Fatalf("second enqueue inserted = %v, %v; want the existing entry", inserted, err)
+	}
+	if ids := pendingIDs(); len(ids) != 1 || ids[0] != matchID {
+		t.Fatalf("pending = %v, want [%s]", ids, matchID)
+	}
+
+	// A failed attempt waits for its backoff
+	err = store.RecordMatchFailure(puuid, matchID, MatchStatusPending, "timeout", 1, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("recording failure: %v", err)
+	}
+	if ids := pendingIDs(); len(ids) != 0 {
+		t.Fatalf("pending during backoff = %v, want none", ids)
+	}
+
+	err = store.RecordMatchFailure(puuid, matchID, MatchStatusFailed, "still failing", 5, time.Now())
+	if err != nil {
+		t.Fatalf("recording failure: %v", err)
+	}
+	failed, err := store.GetFailedMatches(10)
+	if err != nil {
+		t.Fatalf("getting failed matches: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Attempts != 5 || failed[0].LastError != "still failing" {
+		t.Fatalf("failed = %+v, want the match after 5 attempts", failed)
+	}
+
+	reset, err := store.RetryFailedMatch(matchID)
+	if err != nil || reset != 1 {
+		t.Fatalf("retry reset %d entries, %v; want 1", reset, err)
+	}
+	entries, err := store.GetPendingMatches(puuid)
+	if err != nil || len(entries) != 1 || entries[0].Attempts != 0 {
+		t.Fatalf("pending after retry = %+v, %v; want the match with no attempts", entries, err)
+	}
+
+	if err := store.MarkMatchProcessed(puuid, matchID); err != nil {
+		t.Fatalf("marking processed: %v", err)
+	}
+	if ids := pendingIDs(); len(ids) != 0 {
+		t.Fatalf("pending after processing = %v, want none", ids)
+	}
+	if reset, err := store.RetryFailedMatch(matchID); err != nil || reset != 0 {
+		t.Fatalf("retrying a processed match reset %d entries, %v; want 0", reset, err)
+	}
+}
+
+func TestBackfillJobs(t *testing.T) {
+	store := newTestStore(t)
+
+	since := time.Now().AddDate(0, 0, -30).Truncate(time.Second)
+	jobs := []*BackfillJob{
+		{PUUID: "a", Since: since, MaxGames: 100},
+		{PUUID: "b", MaxGames: 20},
+	}
+	for _, job := range jobs {
+		player := &TrackedPlayer{PUUID: job.PUUID, GameName: job.PUUID, TagLine: "NA1", Platform: "na1"}
+		if err := store.AddTrackedPlayer(player); err != nil {
+			t.Fatalf("adding player: %v", err)
+		}
+		if err := store.AddBackfillJob(job); err != nil {
+			t.Fatalf("adding backfill: %v", err)
+		}
+	}
+
+	active, err := store.GetActiveBackfill("a")
+	if err != nil || active == nil {
+		t.Fatalf("active backfill = %+v, %v; want job %d", active, err, jobs[0].ID)
+	}
+	if active.ID != jobs[0].ID || !active.Since.Equal(since) || active.MaxGames != 100 {
+		t.Errorf("active backfill = %+v, want %+v", active, jobs[0])
+	}
+
+	if err := store.UpdateBackfillProgress(jobs[0].ID, 40, 12); err != nil {
+		t.Fatalf("updating progress: %v", err)
+	}
+	if err := store.FinishBackfill(jobs[1].ID, BackfillStatusDone, ""); err != nil {
+		t.Fatalf("finishing backfill: %v", err)
+	}
+
+	pending, err := store.GetPendingBackfills()
+	if err != nil {
+		t.Fatalf("getting pending backfills: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != jobs[0].ID {
+		t.Fatalf("pending = %+v, want only job %d", pending, jobs[0].ID)
+	}
+	if pending[0].NextIndex != 40 || pending[0].Stored != 12 {
+		t.Errorf("progress = %d/%d, want 40/12", pending[0].NextIndex, pending[0].Stored)
+	}
+	if !pending[0].Since.Equal(since) {
+		t.Errorf("since = %v, want %v", pending[0].Since, since)
+	}
+
+	if active, err := store.GetActiveBackfill("b"); err != nil || active != nil {
+		t.Errorf("active backfill for a finished job = %+v, %v; want nil", active, err)
+	}
+}
+
+// addTestMatches stores games for the stats tests, created an hour apart
+// going back from now.
+func addTestMatches(t *testing.T, store *Database, now time.Time, matches []MatchData) {
+	t.Helper()
+	for idx := range matches {
+		match := matches[idx]
+		match.MatchID = fmt.Sprintf("%s_%d", match.PUUID, idx)
+		match.Items = "[]"
+		match.GameCreation = now.Add(-time.Duration(idx+1) * time.Hour)
+		match.setRates()
+		if err := store.AddMatchData(&match); err != nil {
+			t.Fatalf("adding match: %v", err)
+		}
+	}
+}
+
+func TestGetStatGroups(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now()
+	addTestMatches(t, store, now, []MatchData{
+		{PUUID: "p", Champion: "Ahri", TeamPosition: "MIDDLE", GameMode: "CLASSIC", QueueID: 420, GameDuration: 1800,
+			Win: true, Kills: 10, Deaths: 2, Assists: 5, CreepScore: 240, TeamKills: 30},
+		{PUUID: "p", Champion: "Ahri", TeamPosition: "MIDDLE", GameMode: "CLASSIC", QueueID: 420, GameDuration: 1200,
+			Kills: 1, Deaths: 8, Assists: 2, CreepScore: 120},
+		{PUUID: "p", Champion: "Lux", GameMode: "ARAM", QueueID: 450, GameDuration: 900,
+			Win: true, Kills: 5, Deaths: 5, Assists: 20, CreepScore: 30, TeamKills: 50},
+		{PUUID: "q", Champion: "Zed", TeamPosition: "MIDDLE", GameMode: "CLASSIC", QueueID: 420, GameDuration: 1500,
+			Win: true, Kills: 7},
+	})
+
+	type want struct {
+		key          string
+		games, wins  int
+		kills        int
+		kpGames      int
+		killPartRate float64
+	}
+	tests := []struct {
+		name     string
+		filter   StatsFilter
+		grouping StatsGrouping
+		limit    int
+		want     []want
+	}{
+		{
+			name:     "total",
+			filter:   StatsFilter{PUUIDs: []string{"p"}},
+			grouping: StatsTotal,
+			want:     []want{{key: "", games: 3, wins: 2, kills: 16, kpGames: 2, killPartRate: (0.5 + 0.5) / 2}},
+		},
+		{
+			name:     "total without matches",
+			filter:   StatsFilter{PUUIDs: []string{"nobody"}},
+			grouping: StatsTotal,
+			want:     []want{{key: ""}},
+		},
+		{
+			name:     "by champion, most played first",
+			filter:   StatsFilter{PUUIDs: []string{"p"}},
+			grouping: GroupByChampion,
+			want: []want{
+				{key: "Ahri", games: 2, wins: 1, kills: 11, kpGames: 1, killPartRate: 0.5},
+				{key: "Lux", games: 1, wins: 1, kills: 5, kpGames: 1, killPartRate: 0.5},
+			},
+		},
+		{
+			name:     "by role",
+			filter:   StatsFilter{PUUIDs: []string{"p"}},
+			grouping: GroupByRole,
+			want: []want{
+				{key: "MIDDLE", games: 2, wins: 1, kills: 11, kpGames: 1, killPartRate: 0.5},
+				{key: "", games: 1, wins: 1, kills: 5, kpGames: 1, killPartRate: 0.5},
+			},
+		},
+		{
+			name:     "by queue",
+			filter:   StatsFilter{PUUIDs: []string{"p"}},
+			grouping: GroupByQueue,
+			want: []want{
+				{key: "420", games: 2, wins: 1, kills: 11, kpGames: 1, killPartRate: 0.5},
+				{key: "450", games: 1, wins: 1, kills: 5, kpGames: 1, killPartRate: 0.5},
+			},
+		},
+		{
+			name:     "by player in one queue",
+			filter:   StatsFilter{QueueIDs: []int{420}},
+			grouping: GroupByPlayer,
+			want: []want{
+				{key: "p", games: 2, wins: 1, kills: 11, kpGames: 1, killPartRate: 0.5},
+				{key: "q", games: 1, wins: 1, kills: 7},
+			},
+		},
+		{
+			name:     "limited",
+			filter:   StatsFilter{PUUIDs: []string{"p"}},
+			grouping: GroupByChampion,
+			limit:    1,
+			want:     []want{{key: "Ahri", games: 2, wins: 1, kills: 11, kpGames: 1, killPartRate: 0.5}},
+		},
+		{
+			name:     "since",
+			filter:   StatsFilter{PUUIDs: []string{"p"}, Since: now.Add(-150 * time.Minute)},
+			grouping: StatsTotal,
+			want:     []want{{key: "", games: 2, wins: 1, kills: 11, kpGames: 1, killPartRate: 0.5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := store.GetStatGroups(tt.filter, tt.grouping, tt.limit)
+			if err != nil {
+				t.Fatalf("GetStatGroups: %v", err)
+			}
+			if len(groups) != len(tt.want) {
+				t.Fatalf("got %d groups %+v, want %d", len(groups), groups, len(tt.want))
+			}
+			for idx, w := range tt.want {
+				g := groups[idx]
+				if g.Key != w.key || g.Games != w.games || g.Wins != w.wins || g.Kills != w.kills ||
+					g.KillParticipationGames != w.kpGames || g.KillParticipation != w.killPartRate {
+					t.Errorf("group %d = %+v, want %+v", idx, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestGetGamesByPerformance(t *testing.T) {
+	store := newTestStore(t)
+	addTestMatches(t, store, time.Now(), []MatchData{
+		{PUUID: "p", Champion: "Ahri", Kills: 10, Deaths: 1, Assists: 0},
+		{PUUID: "p", Champion: "Lux", Kills: 2, Deaths: 8, Assists: 2},
+		{PUUID: "p", Champion: "Zed", Kills: 1, Deaths: 1, Assists: 0, Win: true},
+		{PUUID: "p", Champion: "Yasuo", Kills: 1, Deaths: 1, Assists: 0},
+	})
+
+	tests := []struct {
+		name  string
+		best  bool
+		limit int
+		want  []string
+	}{
+		{name: "best", best: true, limit: 2, want: []string{"Ahri", "Zed"}},
+		{name: "worst", best: false, limit: 2, want: []string{"Lux", "Yasuo"}},
+		{name: "all", best: true, limit: 10, want: []string{"Ahri", "Zed", "Yasuo", "Lux"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			games, err := store.GetGamesByPerformance(StatsFilter{PUUIDs: []string{"p"}}, tt.best, tt.limit)
+			if err != nil {
+				t.Fatalf("GetGamesByPerformance: %v", err)
+			}
+			var champions []string
+			for _, game := range games {
+				champions = append(champions, game.Champion)
+			}
+			if len(champions) != len(tt.want) {
+				t.Fatalf("got %v, want %v", champions, tt.want)
+			}
+			for idx := range champions {
+				if champions[idx] != tt.want[idx] {
+					t.Fatalf("got %v, want %v", champions, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRankSnapshotLookups(t *testing.T) {
+	store := newTestStore(t)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for idx, lp := range []int{10, 30, 50} {
+		err := store.AddRankSnapshot(&RankSnapshot{
+			PUUID: "p", QueueType: RankedSoloQueue, Tier: "GOLD", Rank: "II", LeaguePoints: lp,
+			Wins: idx, CapturedAt: start.Add(time.Duration(idx) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("adding snapshot: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		after  bool
+		queue  string
+		at     time.Time
+		wantLP int // -1 for no snapshot
+	}{
+		{name: "before, between snapshots", at: start.Add(90 * time.Minute), queue: RankedSoloQueue, wantLP: 30},
+		{name: "before, exact time", at: start.Add(time.Hour), queue: RankedSoloQueue, wantLP: 30},
+		{name: "before the first", at: start.Add(-time.Minute), queue: RankedSoloQueue, wantLP: -1},
+		{name: "after, between snapshots", after: true, at: start.Add(30 * time.Minute), queue: RankedSoloQueue, wantLP: 30},
+		{name: "after, exact time", after: true, at: start, queue: RankedSoloQueue, wantLP: 10},
+		{name: "after the last", after: true, at: start.Add(3 * time.Hour), queue: RankedSoloQueue, wantLP: -1},
+		{name: "other queue", at: start.Add(3 * time.Hour), queue: RankedFlexQueue, wantLP: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := store.GetRankSnapshotBefore
+			if tt.after {
+				lookup = store.GetRankSnapshotAfter
+			}
+			snapshot, err := lookup("p", tt.queue, tt.at)
+			if err != nil {
+				t.Fatalf("lookup: %v", err)
+			}
+			switch {
+			case tt.wantLP < 0 && snapshot != nil:
+				t.Errorf("got %+v, want no snapshot", snapshot)
+			case tt.wantLP >= 0 && (snapshot == nil || snapshot.LeaguePoints != tt.wantLP):
+				t.Errorf("got %+v, want the %d LP snapshot", snapshot, tt.wantLP)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	dataDragonBaseURL      = "https://ddragon.leagueoflegends.com"
+	championCacheRefreshAt = 24 * time.Hour
+	// championCacheRetryAfter spaces out fetches while Data Dragon fails,
+	// so lookups do not each wait for another timeout.
+	championCacheRetryAfter = 5 * time.Minute
+)
+
+// championCache maps champion keys (the numeric IDs used by spectator-v5)
+// to names, loaded from Data Dragon and refreshed daily for new champions.
+type championCache struct {
+	mu       sync.Mutex
+	names    map[int]string
+	loadedAt time.Time
+	loading  bool      // a fetch is in flight
+	retryAt  time.Time // earliest next fetch after a failure
+}
+
+// ChampionName returns the display name for a champion ID. Data Dragon
+// failures fall back to the numeric ID rather than failing the caller. Only
+// the caller that starts a fetch waits for it; others meanwhile get the old
+// names or the fallback.
+func (r *RiotAPI) ChampionName(ctx context.Context, championID int) string {
+	cache := &r.champions
+	cache.mu.Lock()
+	now := time.Now()
+	due := cache.names == nil || now.Sub(cache.loadedAt) > championCacheRefreshAt
+	if due && !cache.loading && !now.Before(cache.retryAt) {
+		cache.loading = true
+		cache.mu.Unlock()
+
+		names, err := r.fetchChampionNames(ctx)
+
+		cache.mu.Lock()
+		cache.loading = false
+		if err != nil {
+			// Keep serving the old names, if any
+			log.Printf("Error loading champion names from Data Dragon, retrying in %s: %v", championCacheRetryAfter, err)
+			cache.retryAt = time.Now().Add(championCacheRetryAfter)
+		} else {
+			cache.names = names
+			cache.loadedAt = time.Now()
+		}
+	}
+	name, ok := cache.names[championID]
+	cache.mu.Unlock()
+
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("Champion %d", championID)
+}
+
+func (r *RiotAPI) fetchChampionNames(ctx context.Context) (map[int]string, error) {
+	var versions []string
+	if err := r.getDataDragon(ctx, dataDragonBaseURL+"/api/versions.json", &versions); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("data dragon returned no versions")
+	}
+
+	var champions struct {
+		Data map[string]struct {
+			Key  string `json:"key"`
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	url := fmt.Sprintf("%s/cdn/%s/data/en_US/champion.json", dataDragonBaseURL, versions[0])
+	if err := r.getDataDragon(ctx, url, &champions); err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string, len(champions.Data))
+	for _, champion := range champions.Data {
+		key, err := strconv.Atoi(champion.Key)
+		if err != nil {
+			continue
+		}
+		names[key] = champion.Name
+	}
+	return names, nil
+}
+
+// getDataDragon fetches static data. Data Dragon is a CDN that needs neither
+// the API key nor the rate limiter.
+func (r *RiotAPI) getDataDragon(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("data dragon request failed with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
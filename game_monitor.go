@@ -1,118 +1,626 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/robfig/cron/v3"
 )
 
+// errPlayerNotInMatch is returned when a match from a player's history does not
+// list them as a participant.
+var errPlayerNotInMatch = errors.New("player not found in match data")
+
+// isPermanentMatchError reports whether retrying a failed match can never
+// succeed, e.g. Riot no longer has it. Everything else is retried with
+// backoff until the attempt limit is reached.
+func isPermanentMatchError(err error) bool {
+	return errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrBadRequest) ||
+		errors.Is(err, errPlayerNotInMatch)
+}
+
+const (
+	// defaultMonitorWorkers is how many players are checked in parallel. All
+	// workers share the RiotAPI rate limiter, so more workers only help while
+	// requests are waiting on Riot rather than on the limiter.
+	defaultMonitorWorkers = 4
+
+	// defaultCatchUpLimit caps how many missed matches are fetched for one
+	// player in a single cycle.
+	defaultCatchUpLimit = 50
+
+	// matchHistoryPageSize is how many match IDs are requested per page.
+	matchHistoryPageSize = 20
+
+	// catchUpSummaryThreshold is the number of new matches above which they
+	// are reported as one catch-up summary instead of individual posts.
+	catchUpSummaryThreshold = 3
+	catchUpSummaryMaxLines  = 25
+
+	// defaultMaxMatchAttempts is how many times a match is tried before it is
+	// marked failed and reported to admins.
+	defaultMaxMatchAttempts = 5
+	matchRetryBaseDelay     = 5 * time.Minute
+	matchRetryMaxDelay      = 6 * time.Hour
+)
+
+// MonitorConfig tunes the GameMonitor; zero values fall back to defaults.
+type MonitorConfig struct {
+	// PollInterval is how often active players are checked; less active
+	// players back off from it (see poll_schedule.go).
+	PollInterval     time.Duration
+	Workers          int
+	CatchUpLimit     int
+	MaxMatchAttempts int
+	// LiveGames posts an embed when a player enters a game and edits it
+	// into the post-game summary once the match is available.
+	LiveGames bool
+	// AccountRefreshInterval is how often each player's Riot ID is re-read
+	// from account-v1 to pick up renames.
+	AccountRefreshInterval time.Duration
+}
+
 type GameMonitor struct {
-	db       *Database
-	riotAPI  *RiotAPI
-	discord  *discordgo.Session
-	cron     *cron.Cron
-	channelID string
+	db           Store
+	riotAPI      *RiotAPI
+	discord      *discordgo.Session
+	cron         *cron.Cron
+	workers      int
+	pollInterval time.Duration
+	catchUpLimit int
+	alerter      *AdminAlerter
+
+	maxMatchAttempts       int
+	liveGames              bool
+	accountRefreshInterval time.Duration
+
+	// running guards against a slow cycle overlapping the next cron tick.
+	running atomic.Bool
+	ctx     context.Context
+	cancel  context.CancelFunc
 }
 
-func NewGameMonitor(db *Database, riotAPI *RiotAPI, discord *discordgo.Session, channelID string) *GameMonitor {
+func NewGameMonitor(db Store, riotAPI *RiotAPI, alerter *AdminAlerter, discord *discordgo.Session, config MonitorConfig) *GameMonitor {
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultMonitorWorkers
+	}
+	if config.CatchUpLimit <= 0 {
+		config.CatchUpLimit = defaultCatchUpLimit
+	}
+	if config.MaxMatchAttempts <= 0 {
+		config.MaxMatchAttempts = defaultMaxMatchAttempts
+	}
+	if config.AccountRefreshInterval <= 0 {
+		config.AccountRefreshInterval = defaultAccountRefreshInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &GameMonitor{
-		db:        db,
-		riotAPI:   riotAPI,
-		discord:   discord,
-		cron:      cron.New(),
-		channelID: channelID,
+		db:           db,
+		riotAPI:      riotAPI,
+		discord:      discord,
+		cron:         cron.New(),
+		workers:      config.Workers,
+		pollInterval: config.PollInterval,
+		catchUpLimit: config.CatchUpLimit,
+		alerter:      alerter,
+
+		maxMatchAttempts:       config.MaxMatchAttempts,
+		liveGames:              config.LiveGames,
+		accountRefreshInterval: config.AccountRefreshInterval,
+		ctx:                    ctx,
+		cancel:                 cancel,
 	}
 }
 
 func (gm *GameMonitor) Start() {
-	gm.cron.AddFunc("@every 5m", gm.checkForNewGames)
+	gm.cron.AddFunc("@every "+gm.pollInterval.String(), gm.checkForNewGames)
 	gm.cron.Start()
-	log.Println("Game monitor started - checking for new games every 5 minutes")
+	log.Printf("Game monitor started - checking active players every %s with %d workers", gm.pollInterval, gm.workers)
 }
 
+// Stop cancels any in-flight cycle and waits for it to wind down.
 func (gm *GameMonitor) Stop() {
-	gm.cron.Stop()
+	gm.cancel()
+	<-gm.cron.Stop().Done()
 	log.Println("Game monitor stopped")
 }
 
 func (gm *GameMonitor) checkForNewGames() {
+	if !gm.running.CompareAndSwap(false, true) {
+		monitorCyclesSkipped.Inc()
+		log.Println("Previous game check is still running, skipping this cycle")
+		return
+	}
+	defer gm.running.Store(false)
+
+	start := time.Now()
+	defer func() {
+		monitorCycleDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := context.WithCancel(gm.ctx)
+	defer cancel()
+
 	players, err := gm.db.GetTrackedPlayers()
 	if err != nil {
 		log.Printf("Error getting tracked players: %v", err)
 		return
 	}
+	players = gm.duePlayers(players, start)
 
+	if gm.liveGames {
+		if err := gm.db.RemoveStaleLiveGames(start.Add(-liveGameTTL)); err != nil {
+			log.Printf("Error removing stale live games: %v", err)
+		}
+	}
+
+	queue := make(chan TrackedPlayer)
+	var wg sync.WaitGroup
+	for w := 0; w < gm.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for player := range queue {
+				gm.checkPlayer(ctx, cancel, player)
+			}
+		}()
+	}
+
+feed:
+	for _, player := range players {
+		select {
+		case queue <- player:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if gm.ctx.Err() != nil {
+		log.Println("Game check cancelled by shutdown")
+	}
+}
+
+// duePlayers filters players down to those whose next check falls before
+// the next tick. Checks are scheduled from when they finish, so without the
+// half-interval slack a player due every tick would only be checked every
+// other tick.
+func (gm *GameMonitor) duePlayers(players []TrackedPlayer, now time.Time) []TrackedPlayer {
+	horizon := now.Add(gm.pollInterval / 2)
+	due := players[:0]
 	for _, player := range players {
-		if err := gm.checkPlayerForNewGames(player); err != nil {
-			log.Printf("Error checking games for %s#%s: %v", player.GameName, player.TagLine, err)
+		if player.NextCheckAt.Before(horizon) {
+			due = append(due, player)
 		}
 	}
+	return due
 }
 
-func (gm *GameMonitor) checkPlayerForNewGames(player TrackedPlayer) error {
-	matchIDs, err := gm.riotAPI.GetMatchHistory(player.PUUID, 5)
+// checkPlayer runs one player's check and reschedules them. It cancels the
+// whole cycle when the error means every remaining call would fail the same
+// way, leaving the rest of the players for the next cycle instead of burning
+// requests.
+func (gm *GameMonitor) checkPlayer(ctx context.Context, cancelCycle context.CancelFunc, player TrackedPlayer) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	monitorPlayersChecked.Inc()
+	player, err := gm.refreshRiotID(ctx, player)
+	if err == nil {
+		err = gm.checkPlayerForNewGames(ctx, player)
+	}
+	if err == nil {
+		err = gm.checkActiveGame(ctx, player)
+	}
+	if err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	log.Printf("Error checking games for %s#%s: %v", player.GameName, player.TagLine, err)
+
+	// Try again at the base interval without changing the player's state.
+	if err := gm.db.UpdatePollSchedule(player.PUUID, player.PollState, time.Now().Add(gm.pollInterval)); err != nil {
+		log.Printf("Error rescheduling %s#%s: %v", player.GameName, player.TagLine, err)
+	}
+
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		log.Println("Riot API key rejected, skipping the rest of this cycle")
+		cancelCycle()
+	case errors.Is(err, ErrRateLimited):
+		log.Printf("Rate limited by Riot (retry after %s), skipping the rest of this cycle", retryAfter(err))
+		cancelCycle()
+	}
+}
+
+// checkActiveGame looks player up in spectator-v5, announces the game if live
+// notifications are on, and reschedules the player's next check.
+func (gm *GameMonitor) checkActiveGame(ctx context.Context, player TrackedPlayer) error {
+	game, err := gm.riotAPI.GetActiveGame(ctx, player.Platform, player.PUUID)
 	if err != nil {
 		return err
 	}
 
-	if len(matchIDs) == 0 {
-		return nil
+	if game != nil && gm.liveGames {
+		if err := gm.announceLiveGame(ctx, player, game); err != nil {
+			log.Printf("Error announcing live game for %s#%s: %v", player.GameName, player.TagLine, err)
+		}
+	}
+	if game != nil {
+		if _, ranked := rankedQueueTypes[game.GameQueueConfigID]; ranked {
+			if err := gm.snapshotRanks(ctx, player); err != nil {
+				log.Printf("Error snapshotting rank for %s#%s: %v", player.GameName, player.TagLine, err)
+			}
+		}
+	}
+
+	return gm.updatePollSchedule(player, game != nil)
+}
+
+// updatePollSchedule decides how soon to check player again: at the base
+// interval while they are in a game or played recently, backing off to
+// hourly and daily checks as their last game gets older.
+func (gm *GameMonitor) updatePollSchedule(player TrackedPlayer, inGame bool) error {
+	lastGame, err := gm.db.GetLastGameTime(player.PUUID)
+	if err != nil {
+		return err
+	}
+	if player.LastMatchAt.After(lastGame) {
+		lastGame = player.LastMatchAt
+	}
+	if lastGame.IsZero() {
+		lastGame = player.CreatedAt
+	}
+
+	now := time.Now()
+	state := classifyPollState(inGame, lastGame, now)
+	if state != player.PollState {
+		log.Printf("%s#%s is now %s", player.GameName, player.TagLine, state)
+	}
+
+	return gm.db.UpdatePollSchedule(player.PUUID, state, now.Add(pollInterval(state, gm.pollInterval)))
+}
+
+func (gm *GameMonitor) checkPlayerForNewGames(ctx context.Context, player TrackedPlayer) error {
+	newMatchIDs, err := gm.findNewMatches(ctx, player)
+	if err != nil {
+		return err
+	}
+
+	// Persist every discovered match before doing any work on it, oldest
+	// first, so nothing is lost if processing fails or the bot restarts.
+	queued := make(map[string]*QueuedMatch, len(newMatchIDs))
+	inserted := 0
+	for idx := len(newMatchIDs) - 1; idx >= 0; idx-- {
+		entry, isNew, err := gm.db.EnqueueMatch(player.PUUID, newMatchIDs[idx])
+		if err != nil {
+			return err
+		}
+		queued[entry.MatchID] = entry
+		if isNew {
+			inserted++
+			monitorNewMatches.Inc()
+		}
+	}
+
+	// Pending entries include retries of earlier failures, not just the
+	// matches discovered above.
+	pending, err := gm.db.GetPendingMatches(player.PUUID)
+	if err != nil {
+		return err
+	}
+
+	// A long gap (lots of games, or the bot was down) gets one summary embed
+	// instead of flooding the channel with individual posts.
+	catchingUp := inserted > catchUpSummaryThreshold
+	var processed []*MatchData
+	defer func() {
+		if catchingUp && len(processed) > 0 {
+			gm.sendCatchUpSummary(player, processed)
+		}
+	}()
+
+	matchTimes := make(map[string]time.Time)
+	for idx := range pending {
+		entry := &pending[idx]
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		matchData, err := gm.processQueuedMatch(ctx, player, entry, !catchingUp)
+		if err != nil {
+			return err
+		}
+		if matchData != nil {
+			matchTimes[entry.MatchID] = matchData.GameCreation
+			if catchingUp {
+				processed = append(processed, matchData)
+			}
+		}
+		if _, ok := queued[entry.MatchID]; ok {
+			queued[entry.MatchID] = entry
+		}
+	}
+
+	return gm.advanceCursor(player, newMatchIDs, queued, matchTimes)
+}
+
+// processQueuedMatch makes one attempt at a queued match and records the
+// outcome in the queue, updating entry in place. It only returns an error
+// when the rest of the player's check should be abandoned (the key was
+// rejected, Riot is rate limiting us, shutdown, or the queue itself failed);
+// those do not count as attempts against the match.
+func (gm *GameMonitor) processQueuedMatch(ctx context.Context, player TrackedPlayer, entry *QueuedMatch, announce bool) (*MatchData, error) {
+	matchData, err := gm.processNewMatch(ctx, player, entry.MatchID)
+	if err == nil {
+		// Catch-up summaries have no rank line, so skip the lookup for them.
+		var rank *RankChange
+		if announce {
+			rank = gm.rankChange(ctx, player, matchData)
+		}
+		err = gm.announceMatch(player, matchData, rank, announce)
 	}
 
-	latestMatchID := matchIDs[0]
-	if latestMatchID == player.LastMatchID {
+	if err == nil {
+		if err := gm.db.MarkMatchProcessed(player.PUUID, entry.MatchID); err != nil {
+			return nil, err
+		}
+		entry.Status = MatchStatusProcessed
+		return matchData, nil
+	}
+
+	if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited) || ctx.Err() != nil {
+		return nil, fmt.Errorf("processing match %s: %w", entry.MatchID, err)
+	}
+
+	entry.Attempts++
+	entry.LastError = err.Error()
+	entry.NextAttemptAt = time.Now().Add(matchRetryBackoff(entry.Attempts))
+	if isPermanentMatchError(err) || entry.Attempts >= gm.maxMatchAttempts {
+		entry.Status = MatchStatusFailed
+		monitorMatchesFailed.Inc()
+		log.Printf("Giving up on match %s for %s#%s after %d attempts: %v",
+			entry.MatchID, player.GameName, player.TagLine, entry.Attempts, err)
+		if gm.alerter != nil {
+			gm.alerter.Notify("match_failed:"+entry.MatchID, fmt.Sprintf(
+				"Match %s for %s#%s failed %d times (%v), see /admin failedmatches",
+				entry.MatchID, player.GameName, player.TagLine, entry.Attempts, err))
+		}
+	} else {
+		log.Printf("Error processing match %s (attempt %d, retrying after %s): %v",
+			entry.MatchID, entry.Attempts, entry.NextAttemptAt.Format(time.RFC3339), err)
+	}
+
+	if err := gm.db.RecordMatchFailure(player.PUUID, entry.MatchID, entry.Status, entry.LastError,
+		entry.Attempts, entry.NextAttemptAt); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// announceMatch posts the summary for a processed match to every guild that
+// tracks the player. Live messages for the game are always edited into the
+// summary, even when announce is false because the match is part of a
+// catch-up summary.
+func (gm *GameMonitor) announceMatch(player TrackedPlayer, match *MatchData, rank *RankChange, announce bool) error {
+	lives, err := gm.db.GetLiveGames(player.PUUID, match.MatchID)
+	if err != nil {
+		return err
+	}
+	if len(lives) == 0 && !announce {
 		return nil
 	}
 
-	for _, matchID := range matchIDs {
-		if matchID == player.LastMatchID {
-			break
+	channels, err := gm.db.GetPlayerChannels(player.PUUID)
+	if err != nil {
+		return err
+	}
+
+	liveByChannel := make(map[string]*LiveGame, len(lives))
+	for idx := range lives {
+		liveByChannel[lives[idx].ChannelID] = &lives[idx]
+		// A guild may have changed channel since the live message went out.
+		if !containsString(channels, lives[idx].ChannelID) {
+			channels = append(channels, lives[idx].ChannelID)
 		}
+	}
 
-		if err := gm.processNewMatch(player, matchID); err != nil {
-			log.Printf("Error processing match %s: %v", matchID, err)
+	embed := buildGameSummaryEmbed(player, match, rank)
+	sent := 0
+	var sendErr error
+	for _, channelID := range channels {
+		live := liveByChannel[channelID]
+		if live == nil && !announce {
 			continue
 		}
+		if err := gm.sendGameSummary(channelID, embed, live); err != nil {
+			log.Printf("Error sending game summary for %s#%s to %s: %v", player.GameName, player.TagLine, channelID, err)
+			sendErr = err
+			continue
+		}
+		sent++
+	}
+
+	if len(lives) > 0 {
+		if err := gm.db.RemoveLiveGame(player.PUUID, match.MatchID); err != nil {
+			return err
+		}
+	}
+	// Only retry the match when nothing got through; otherwise a retry would
+	// repost it in the channels that did.
+	if sent == 0 && sendErr != nil {
+		return sendErr
+	}
+	return nil
+}
+
+// advanceCursor moves last_match_id forward over the newly discovered
+// matches (newest first) for as long as they are done, so a match still
+// waiting for a retry is rediscovered until it has been dealt with.
+func (gm *GameMonitor) advanceCursor(player TrackedPlayer, newMatchIDs []string, queued map[string]*QueuedMatch, matchTimes map[string]time.Time) error {
+	cursor := ""
+	var cursorTime time.Time
+	for idx := len(newMatchIDs) - 1; idx >= 0; idx-- {
+		entry := queued[newMatchIDs[idx]]
+		if entry == nil || !entry.Done() {
+			break
+		}
+		cursor = entry.MatchID
+		if t, ok := matchTimes[cursor]; ok {
+			cursorTime = t
+		}
+	}
+
+	if cursor == "" {
+		return nil
+	}
+	return gm.db.UpdateLastMatchID(player.PUUID, cursor, cursorTime)
+}
+
+// matchRetryBackoff is the delay before the next attempt at a match that has
+// failed attempts times: 5m, 10m, 20m, ... capped at 6h.
+func matchRetryBackoff(attempts int) time.Duration {
+	backoff := matchRetryBaseDelay
+	for i := 1; i < attempts && backoff < matchRetryMaxDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > matchRetryMaxDelay {
+		backoff = matchRetryMaxDelay
+	}
+	return backoff
+}
+
+// findNewMatches pages back through a player's match history until it reaches
+// their stored last match, bounded by its timestamp (when known) and by the
+// configured catch-up limit. IDs are returned newest first.
+func (gm *GameMonitor) findNewMatches(ctx context.Context, player TrackedPlayer) ([]string, error) {
+	var newMatchIDs []string
+	for start := 0; len(newMatchIDs) < gm.catchUpLimit; start += matchHistoryPageSize {
+		page, err := gm.riotAPI.GetMatchIDs(ctx, player.Platform, player.PUUID, MatchHistoryQuery{
+			Start:     start,
+			Count:     matchHistoryPageSize,
+			StartTime: player.LastMatchAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, matchID := range page {
+			if matchID == player.LastMatchID {
+				return newMatchIDs, nil
+			}
+			newMatchIDs = append(newMatchIDs, matchID)
+			if len(newMatchIDs) == gm.catchUpLimit {
+				break
+			}
+		}
+
+		if len(page) < matchHistoryPageSize {
+			return newMatchIDs, nil
+		}
 	}
 
-	return gm.db.UpdateLastMatchID(player.PUUID, latestMatchID)
+	log.Printf("%s#%s has more than %d new matches, older ones will not be posted",
+		player.GameName, player.TagLine, gm.catchUpLimit)
+	return newMatchIDs, nil
 }
 
-func (gm *GameMonitor) processNewMatch(player TrackedPlayer, matchID string) error {
-	match, err := gm.riotAPI.GetMatchDetails(matchID)
+// processNewMatch stores a match for player and returns the extracted row.
+func (gm *GameMonitor) processNewMatch(ctx context.Context, player TrackedPlayer, matchID string) (*MatchData, error) {
+	match, err := loadMatch(ctx, gm.db, gm.riotAPI, player.Platform, matchID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	matchData := gm.riotAPI.ExtractPlayerData(match, player.PUUID)
+	matchData := match.PlayerData(player.PUUID)
 	if matchData == nil {
-		return fmt.Errorf("player not found in match data")
+		return nil, errPlayerNotInMatch
 	}
 
 	if err := gm.db.AddMatchData(matchData); err != nil {
-		return err
+		return nil, err
 	}
 
-	gm.sendGameSummary(player, matchData)
-	return nil
+	return matchData, nil
 }
 
-func (gm *GameMonitor) sendGameSummary(player TrackedPlayer, match *MatchData) {
-	if gm.channelID == "" {
+// sendCatchUpSummary posts one embed covering several matches, oldest first,
+// to every guild that tracks the player.
+func (gm *GameMonitor) sendCatchUpSummary(player TrackedPlayer, matches []*MatchData) {
+	channels, err := gm.db.GetPlayerChannels(player.PUUID)
+	if err != nil {
+		log.Printf("Error getting channels for %s#%s: %v", player.GameName, player.TagLine, err)
+		return
+	}
+	if len(channels) == 0 {
 		return
 	}
 
+	wins := 0
+	var lines strings.Builder
+	for idx, match := range matches {
+		result := "🔴"
+		if match.Win {
+			result = "🟢"
+			wins++
+		}
+		// Stay well inside Discord's 4096 character description limit.
+		if idx < catchUpSummaryMaxLines {
+			lines.WriteString(fmt.Sprintf("%s **%s** %d/%d/%d • %s • %s\n", result, match.Champion,
+				match.Kills, match.Deaths, match.Assists,
+				matchQueueName(match),
+				match.GameCreation.Format("Jan 2 15:04")))
+		}
+	}
+	if len(matches) > catchUpSummaryMaxLines {
+		lines.WriteString(fmt.Sprintf("…and %d more\n", len(matches)-catchUpSummaryMaxLines))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("⏩ Caught up %d games - %s#%s", len(matches), player.GameName, player.TagLine),
+		Description: lines.String(),
+		Color:       0x0099FF,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Record",
+				Value:  fmt.Sprintf("%dW %dL", wins, len(matches)-wins),
+				Inline: true,
+			},
+		},
+		Timestamp: matches[len(matches)-1].GameCreation.Format(time.RFC3339),
+	}
+
+	for _, channelID := range channels {
+		if _, err := gm.discord.ChannelMessageSendEmbed(channelID, embed); err != nil {
+			discordSendFailures.Inc()
+			log.Printf("Error sending catch-up summary to %s: %v", channelID, err)
+		}
+	}
+}
+
+// buildGameSummaryEmbed renders match's summary. rank is nil for unranked
+// games.
+func buildGameSummaryEmbed(player TrackedPlayer, match *MatchData, rank *RankChange) *discordgo.MessageEmbed {
 	winStatus := "🔴 Loss"
 	if match.Win {
 		winStatus = "🟢 Win"
 	}
 
 	kda := fmt.Sprintf("%.2f", float64(match.Kills+match.Assists)/float64(max(match.Deaths, 1)))
-	
+
 	embed := &discordgo.MessageEmbed{
 		Title: fmt.Sprintf("🎮 New Game Detected - %s#%s", player.GameName, player.TagLine),
 		Color: func() int {
@@ -129,32 +637,32 @@ func (gm *GameMonitor) sendGameSummary(player TrackedPlayer, match *MatchData) {
 			},
 			{
 				Name:   "Champion",
-				Value:  match.Champion,
+				Value:  nonEmpty(match.Champion),
 				Inline: true,
 			},
 			{
 				Name:   "KDA",
-				Value:  fmt.Sprintf("%d/%d/%d (%.2s)", match.Kills, match.Deaths, match.Assists, kda),
+				Value:  fmt.Sprintf("%d/%d/%d (%s)", match.Kills, match.Deaths, match.Assists, kda),
 				Inline: true,
 			},
 			{
 				Name:   "CS",
-				Value:  fmt.Sprintf("%d", match.CreepScore),
+				Value:  fmt.Sprintf("%d (%.1f/min)", match.CreepScore, match.CSPerMinute),
 				Inline: true,
 			},
 			{
 				Name:   "Damage",
-				Value:  fmt.Sprintf("%,d", match.DamageDealt),
+				Value:  fmt.Sprintf("%s (%.0f/min)", formatThousands(match.DamageDealt), match.DamagePerMinute),
 				Inline: true,
 			},
 			{
 				Name:   "Vision Score",
-				Value:  fmt.Sprintf("%d", match.VisionScore),
+				Value:  fmt.Sprintf("%d (%.2f/min)", match.VisionScore, match.VisionPerMinute),
 				Inline: true,
 			},
 			{
-				Name:   "Game Mode",
-				Value:  strings.Title(strings.ReplaceAll(match.GameMode, "_", " ")),
+				Name:   "Queue",
+				Value:  matchQueueName(match),
 				Inline: true,
 			},
 			{
@@ -164,7 +672,7 @@ func (gm *GameMonitor) sendGameSummary(player TrackedPlayer, match *MatchData) {
 			},
 			{
 				Name:   "Gold Earned",
-				Value:  fmt.Sprintf("%,d", match.GoldEarned),
+				Value:  fmt.Sprintf("%s (%.0f/min)", formatThousands(match.GoldEarned), match.GoldPerMinute),
 				Inline: true,
 			},
 		},
@@ -174,10 +682,52 @@ func (gm *GameMonitor) sendGameSummary(player TrackedPlayer, match *MatchData) {
 		},
 	}
 
-	_, err := gm.discord.ChannelMessageSendEmbed(gm.channelID, embed)
+	if match.TeamKills > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Kill Participation",
+			Value:  fmt.Sprintf("%.0f%%", match.KillParticipation*100),
+			Inline: true,
+		})
+	}
+
+	if rank != nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Rank",
+			Value: rank.String(),
+		})
+		embed.Description = rank.Callout()
+	}
+
+	return embed
+}
+
+// sendGameSummary posts embed to channelID, or edits live's "in game" message
+// into it when there is one.
+func (gm *GameMonitor) sendGameSummary(channelID string, embed *discordgo.MessageEmbed, live *LiveGame) error {
+	if live != nil {
+		_, err := gm.discord.ChannelMessageEditEmbed(live.ChannelID, live.MessageID, embed)
+		if err == nil {
+			return nil
+		}
+		// The live message may have been deleted; post a fresh summary.
+		log.Printf("Error editing live game message %s, posting a new summary: %v", live.MessageID, err)
+	}
+
+	_, err := gm.discord.ChannelMessageSendEmbed(channelID, embed)
 	if err != nil {
-		log.Printf("Error sending game summary: %v", err)
+		discordSendFailures.Inc()
+		return fmt.Errorf("sending game summary: %w", err)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
 }
 
 func max(a, b int) int {
@@ -185,4 +735,33 @@ func max(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
+
+// truncate shortens s to at most n characters (not bytes, so multi-byte
+// characters are never split), marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// formatThousands renders n with comma separators, e.g. 12345 -> "12,345".
+func formatThousands(n int) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+
+	digits := fmt.Sprintf("%d", n)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 5 * time.Minute},
+		{attempts: 1, want: 5 * time.Minute},
+		{attempts: 2, want: 10 * time.Minute},
+		{attempts: 3, want: 20 * time.Minute},
+		{attempts: 7, want: 320 * time.Minute},
+		{attempts: 8, want: matchRetryMaxDelay},
+		{attempts: 100, want: matchRetryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := matchRetryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("matchRetryBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestAdvanceCursor(t *testing.T) {
+	old := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	matchTimes := map[string]time.Time{
+		"NA1_1": old.Add(time.Hour),
+		"NA1_2": old.Add(2 * time.Hour),
+		"NA1_3": old.Add(3 * time.Hour),
+	}
+	// Newest first, as findNewMatches returns them
+	newMatchIDs := []string{"NA1_3", "NA1_2", "NA1_1"}
+
+	tests := []struct {
+		name       string
+		statuses   map[string]string
+		wantCursor string
+		wantTime   time.Time
+	}{
+		{
+			name: "all done",
+			statuses: map[string]string{
+				"NA1_1": MatchStatusProcessed, "NA1_2": MatchStatusFailed, "NA1_3": MatchStatusProcessed,
+			},
+			wantCursor: "NA1_3",
+			wantTime:   matchTimes["NA1_3"],
+		},
+		{
+			name: "stops before a pending match",
+			statuses: map[string]string{
+				"NA1_1": MatchStatusProcessed, "NA1_2": MatchStatusPending, "NA1_3": MatchStatusProcessed,
+			},
+			wantCursor: "NA1_1",
+			wantTime:   matchTimes["NA1_1"],
+		},
+		{
+			name:       "oldest still pending",
+			statuses:   map[string]string{"NA1_1": MatchStatusPending, "NA1_2": MatchStatusProcessed},
+			wantCursor: "NA1_0",
+			wantTime:   old,
+		},
+		{
+			name:       "oldest never queued",
+			statuses:   map[string]string{"NA1_2": MatchStatusProcessed, "NA1_3": MatchStatusProcessed},
+			wantCursor: "NA1_0",
+			wantTime:   old,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			player := TrackedPlayer{PUUID: "p", GameName: "Player", TagLine: "NA1", Platform: "na1",
+				LastMatchID: "NA1_0", LastMatchAt: old}
+			if err := store.AddTrackedPlayer(&player); err != nil {
+				t.Fatalf("adding player: %v", err)
+			}
+
+			queued := make(map[string]*QueuedMatch)
+			for matchID, status := range tt.statuses {
+				queued[matchID] = &QueuedMatch{PUUID: player.PUUID, MatchID: matchID, Status: status}
+			}
+
+			gm := &GameMonitor{db: store}
+			if err := gm.advanceCursor(player, newMatchIDs, queued, matchTimes); err != nil {
+				t.Fatalf("advanceCursor: %v", err)
+			}
+
+			stored, err := store.GetTrackedPlayer(player.PUUID)
+			if err != nil {
+				t.Fatalf("getting player: %v", err)
+			}
+			if stored.LastMatchID != tt.wantCursor || !stored.LastMatchAt.Equal(tt.wantTime) {
+				t.Errorf("cursor = %s at %v, want %s at %v", stored.LastMatchID, stored.LastMatchAt,
+					tt.wantCursor, tt.wantTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// manageGuildPermissions hides /config from members who cannot manage the
+// server by default; the command's Permission still checks canConfigureGuild.
+var manageGuildPermissions int64 = discordgo.PermissionManageServer
+
+// canConfigureGuild reports whether the invoking member may change the
+// guild's bot settings: server managers and bot admins.
+func canConfigureGuild(i *discordgo.InteractionCreate) bool {
+	if isBotAdmin(i) {
+		return true
+	}
+	return i.Member != nil && i.Member.Permissions&discordgo.PermissionManageServer != 0
+}
+
+// adoptLegacyChannel carries a deployment from before per-guild settings
+// over: MONITOR_CHANNEL_ID's guild gets it as its notification channel (if it
+// has none yet) and takes over every player no guild tracks.
+func adoptLegacyChannel(s *discordgo.Session, channelID string) {
+	if channelID == "" {
+		return
+	}
+
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		log.Printf("Error looking up MONITOR_CHANNEL_ID %s: %v", channelID, err)
+		return
+	}
+	if channel.GuildID == "" {
+		log.Printf("MONITOR_CHANNEL_ID %s is not a server channel, ignoring it", channelID)
+		return
+	}
+
+	settings, err := db.GetGuildSettings(channel.GuildID)
+	if err != nil {
+		log.Printf("Error getting settings for guild %s: %v", channel.GuildID, err)
+		return
+	}
+	if settings == nil || settings.ChannelID == "" {
+		if err := db.SetGuildChannel(channel.GuildID, channelID); err != nil {
+			log.Printf("Error setting channel for guild %s: %v", channel.GuildID, err)
+			return
+		}
+		log.Printf("Using MONITOR_CHANNEL_ID %s as the notification channel for guild %s", channelID, channel.GuildID)
+	}
+
+	adopted, err := db.AdoptUnscopedPlayers(channel.GuildID)
+	if err != nil {
+		log.Printf("Error assigning tracked players to guild %s: %v", channel.GuildID, err)
+		return
+	}
+	if adopted > 0 {
+		log.Printf("Assigned %d previously tracked players to guild %s", adopted, channel.GuildID)
+	}
+}
+
+// channelHint reminds the user to pick a notification channel when guildID
+// has none, since nothing is posted until it does.
+func channelHint(guildID string) string {
+	settings, err := db.GetGuildSettings(guildID)
+	if err != nil || (settings != nil && settings.ChannelID != "") {
+		return ""
+	}
+	return "\n⚠️ No notification channel is set for this server yet, use `/config channel` to pick one"
+}
+
+func handleConfigCommand(c *CommandContext) error {
+	switch c.Subcommand() {
+	case "channel":
+		return handleConfigChannelCommand(c)
+	case "show":
+		return handleConfigShowCommand(c)
+	}
+	return nil
+}
+
+func handleConfigChannelCommand(c *CommandContext) error {
+	channel := c.ChannelOption("channel")
+	if err := db.SetGuildChannel(c.GuildID(), channel.ID); err != nil {
+		return fmt.Errorf("saving settings: %w", err)
+	}
+	return c.Respond(fmt.Sprintf("✅ Game summaries for this server's tracked players will be posted in <#%s>", channel.ID))
+}
+
+func handleConfigShowCommand(c *CommandContext) error {
+	settings, err := db.GetGuildSettings(c.GuildID())
+	if err != nil {
+		return fmt.Errorf("getting settings: %w", err)
+	}
+
+	channel := "not set - use `/config channel` to choose one"
+	if settings != nil && settings.ChannelID != "" {
+		channel = fmt.Sprintf("<#%s>", settings.ChannelID)
+	}
+
+	return c.RespondEmbed(&discordgo.MessageEmbed{
+		Title: "⚙️ Server Settings",
+		Color: 0x0099FF,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Notification Channel",
+				Value: channel,
+			},
+		},
+	})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DevKeyLifetime is how long a Riot development key stays valid after it is
+// generated.
+const DevKeyLifetime = 24 * time.Hour
+
+const (
+	keyFilePollInterval   = 30 * time.Second
+	keyExpiryPollInterval = 10 * time.Minute
+	defaultKeyExpiryWarn  = 3 * time.Hour
+)
+
+// KeyManager rotates the Riot API key at runtime, from /admin setkey or from a
+// mounted secret file that is re-read on SIGHUP or when it changes, and warns
+// admins before the current key expires.
+type KeyManager struct {
+	riotAPI *RiotAPI
+	alerter *AdminAlerter
+
+	// filePath is optional; fileKeyLifetime is applied to the file's
+	// modification time (zero means keys from the file never expire).
+	filePath        string
+	fileKeyLifetime time.Duration
+	warnBefore      time.Duration
+
+	mu          sync.Mutex
+	fileModTime time.Time
+	warnedFor   time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewKeyManager(riotAPI *RiotAPI, alerter *AdminAlerter, filePath string, fileKeyLifetime, warnBefore time.Duration) *KeyManager {
+	if warnBefore <= 0 {
+		warnBefore = defaultKeyExpiryWarn
+	}
+	return &KeyManager{
+		riotAPI:         riotAPI,
+		alerter:         alerter,
+		filePath:        filePath,
+		fileKeyLifetime: fileKeyLifetime,
+		warnBefore:      warnBefore,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// SetKey validates apiKey and, if Riot accepts it, swaps it in. A zero
+// expiresAt means the key does not expire.
+func (km *KeyManager) SetKey(ctx context.Context, apiKey string, expiresAt time.Time) error {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return fmt.Errorf("API key is empty")
+	}
+
+	if err := km.riotAPI.ValidateKey(ctx, apiKey); err != nil {
+		return fmt.Errorf("validating API key: %w", err)
+	}
+
+	km.riotAPI.SetAPIKey(apiKey, expiresAt)
+
+	km.mu.Lock()
+	km.warnedFor = time.Time{}
+	km.mu.Unlock()
+	km.alerter.Clear(alertKindAuth)
+
+	if expiresAt.IsZero() {
+		log.Println("Riot API key rotated (no expiry)")
+	} else {
+		log.Printf("Riot API key rotated, expires at %s", expiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ReloadFile reads the key file and swaps the key in if it differs from the
+// current one.
+func (km *KeyManager) ReloadFile(ctx context.Context) error {
+	if km.filePath == "" {
+		return fmt.Errorf("no API key file configured")
+	}
+
+	info, err := os.Stat(km.filePath)
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(km.filePath)
+	if err != nil {
+		return err
+	}
+
+	km.mu.Lock()
+	km.fileModTime = info.ModTime()
+	km.mu.Unlock()
+
+	apiKey := strings.TrimSpace(string(contents))
+	if apiKey == km.riotAPI.APIKey() {
+		return nil
+	}
+
+	var expiresAt time.Time
+	if km.fileKeyLifetime > 0 {
+		expiresAt = info.ModTime().Add(km.fileKeyLifetime)
+	}
+	return km.SetKey(ctx, apiKey, expiresAt)
+}
+
+// Start loads the key file (if any) and begins watching for SIGHUP, key file
+// changes and upcoming expiry.
+func (km *KeyManager) Start() {
+	if km.filePath != "" {
+		if err := km.ReloadFile(context.Background()); err != nil {
+			log.Printf("Error loading Riot API key file %s: %v", km.filePath, err)
+		}
+	}
+
+	go km.run()
+	log.Println("Key manager started")
+}
+
+func (km *KeyManager) Stop() {
+	close(km.stop)
+	<-km.done
+}
+
+func (km *KeyManager) run() {
+	defer close(km.done)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	fileTicker := time.NewTicker(keyFilePollInterval)
+	defer fileTicker.Stop()
+	expiryTicker := time.NewTicker(keyExpiryPollInterval)
+	defer expiryTicker.Stop()
+
+	for {
+		select {
+		case <-km.stop:
+			return
+		case <-hup:
+			log.Println("SIGHUP received, reloading Riot API key file")
+			if km.filePath == "" {
+				log.Println("RIOT_API_KEY_FILE is not set, nothing to reload")
+				continue
+			}
+			if err := km.ReloadFile(context.Background()); err != nil {
+				log.Printf("Error reloading Riot API key file: %v", err)
+			}
+		case <-fileTicker.C:
+			if km.fileChanged() {
+				if err := km.ReloadFile(context.Background()); err != nil {
+					log.Printf("Error reloading Riot API key file: %v", err)
+				}
+			}
+		case <-expiryTicker.C:
+			km.checkExpiry()
+		}
+	}
+}
+
+func (km *KeyManager) fileChanged() bool {
+	if km.filePath == "" {
+		return false
+	}
+	info, err := os.Stat(km.filePath)
+	if err != nil {
+		return false
+	}
+
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return !info.ModTime().Equal(km.fileModTime)
+}
+
+// checkExpiry warns the admin channel once per key when it is about to expire.
+func (km *KeyManager) checkExpiry() {
+	expiresAt := km.riotAPI.APIKeyExpiresAt()
+	if expiresAt.IsZero() {
+		return
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining > km.warnBefore {
+		return
+	}
+
+	km.mu.Lock()
+	if km.warnedFor.Equal(expiresAt) {
+		km.mu.Unlock()
+		return
+	}
+	km.warnedFor = expiresAt
+	km.mu.Unlock()
+
+	km.alerter.Clear(alertKindKeyExpiry)
+	if remaining <= 0 {
+		km.alerter.Notify(alertKindKeyExpiry, "Riot API key has expired, rotate it with /admin setkey")
+		return
+	}
+	km.alerter.Notify(alertKindKeyExpiry, fmt.Sprintf("Riot API key expires in %s (%s), rotate it with /admin setkey",
+		remaining.Round(time.Minute), expiresAt.Format(time.RFC1123)))
+}
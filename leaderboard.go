@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// leaderboardComponent prefixes the custom IDs of /leaderboard's page
+	// buttons, see leaderboardQuery.customID.
+	leaderboardComponent = "leaderboard"
+
+	leaderboardPageSize        = 10
+	defaultLeaderboardDays     = 7
+	maxLeaderboardDays         = 365
+	defaultLeaderboardMinGames = 5
+	maxLeaderboardMinGames     = 100
+)
+
+var minLeaderboardValue = 1.0
+
+// leaderboardMetric is a /leaderboard metric option value. Players are
+// ranked by score, highest first; LP is ranked from rank snapshots instead
+// (see lpLeaderboard), so it has neither.
+type leaderboardMetric struct {
+	Value  string
+	Name   string
+	score  func(g *StatGroup) float64
+	format func(g *StatGroup) string
+}
+
+const lpMetric = "lp"
+
+var leaderboardMetrics = []leaderboardMetric{
+	{
+		Value: "winrate",
+		Name:  "Win Rate",
+		score: (*StatGroup).WinRate,
+		format: func(g *StatGroup) string {
+			return fmt.Sprintf("%.1f%% (%dW %dL)", g.WinRate(), g.Wins, g.Games-g.Wins)
+		},
+	},
+	{
+		Value: "kda",
+		Name:  "KDA",
+		score: (*StatGroup).KDA,
+		format: func(g *StatGroup) string {
+			games := float64(g.Games)
+			return fmt.Sprintf("%.2f (%.1f/%.1f/%.1f)", g.KDA(),
+				float64(g.Kills)/games, float64(g.Deaths)/games, float64(g.Assists)/games)
+		},
+	},
+	{
+		Value: "games",
+		Name:  "Games Played",
+		score: func(g *StatGroup) float64 { return float64(g.Games) },
+		format: func(g *StatGroup) string {
+			return fmt.Sprintf("%d games (%.0f%% WR)", g.Games, g.WinRate())
+		},
+	},
+	{
+		Value: "cs_min",
+		Name:  "CS per Minute",
+		score: func(g *StatGroup) float64 { return g.CSPerMinute },
+		format: func(g *StatGroup) string {
+			return fmt.Sprintf("%.1f CS/min", g.CSPerMinute)
+		},
+	},
+	{
+		Value: lpMetric,
+		Name:  "LP Gained",
+	},
+	{
+		Value: "vision",
+		Name:  "Vision per Minute",
+		score: func(g *StatGroup) float64 { return g.VisionPerMinute },
+		format: func(g *StatGroup) string {
+			return fmt.Sprintf("%.2f vision/min", g.VisionPerMinute)
+		},
+	},
+}
+
+func findLeaderboardMetric(value string) *leaderboardMetric {
+	for idx := range leaderboardMetrics {
+		if leaderboardMetrics[idx].Value == value {
+			return &leaderboardMetrics[idx]
+		}
+	}
+	return nil
+}
+
+func leaderboardMetricChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(leaderboardMetrics))
+	for _, metric := range leaderboardMetrics {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  metric.Name,
+			Value: metric.Value,
+		})
+	}
+	return choices
+}
+
+// leaderboardQuery is one page of a leaderboard. Page buttons carry it in
+// their custom IDs, so turning a page needs no state on our side.
+type leaderboardQuery struct {
+	Metric   string
+	Days     int
+	Queue    string // queueFilter value, "" for all queues
+	MinGames int
+	Page     int
+}
+
+func (q leaderboardQuery) customID() string {
+	return fmt.Sprintf("%s:%s:%d:%s:%d:%d", leaderboardComponent, q.Metric, q.Days, q.Queue, q.MinGames, q.Page)
+}
+
+func parseLeaderboardQuery(customID string) (leaderboardQuery, error) {
+	parts := strings.Split(customID, ":")
+	if len(parts) != 6 || parts[0] != leaderboardComponent {
+		return leaderboardQuery{}, fmt.Errorf("malformed leaderboard button %q", customID)
+	}
+	days, daysErr := strconv.Atoi(parts[2])
+	minGames, minGamesErr := strconv.Atoi(parts[4])
+	page, pageErr := strconv.Atoi(parts[5])
+	if daysErr != nil || minGamesErr != nil || pageErr != nil {
+		return leaderboardQuery{}, fmt.Errorf("malformed leaderboard button %q", customID)
+	}
+	return leaderboardQuery{Metric: parts[1], Days: days, Queue: parts[3], MinGames: minGames, Page: page}, nil
+}
+
+// leaderboardEntry is one ranked player; Text renders their score.
+type leaderboardEntry struct {
+	Player TrackedPlayer
+	Score  float64
+	Games  int
+	Text   string
+}
+
+func handleLeaderboardCommand(c *CommandContext) error {
+	q := leaderboardQuery{
+		Metric:   c.StringOption("metric"),
+		Days:     c.IntOption("days", defaultLeaderboardDays),
+		Queue:    c.StringOption("queue"),
+		MinGames: c.IntOption("min_games", defaultLeaderboardMinGames),
+	}
+
+	embed, components, err := renderLeaderboard(c.GuildID(), q)
+	if err != nil {
+		return err
+	}
+	return c.RespondComplex("", []*discordgo.MessageEmbed{embed}, components)
+}
+
+// handleLeaderboardPage turns a leaderboard's page. The standings are
+// recomputed, so they may have moved since the message was sent.
+func handleLeaderboardPage(c *CommandContext) error {
+	q, err := parseLeaderboardQuery(c.Interaction.MessageComponentData().CustomID)
+	if err != nil {
+		return err
+	}
+
+	embed, components, err := renderLeaderboard(c.GuildID(), q)
+	if err != nil {
+		return err
+	}
+	if components == nil {
+		// Down to one page: remove the buttons rather than keep them
+		components = []discordgo.MessageComponent{}
+	}
+	return c.UpdateMessage([]*discordgo.MessageEmbed{embed}, components)
+}
+
+// renderLeaderboard ranks the guild's players for q and renders q.Page, with
+// page buttons when there is more than one.
+func renderLeaderboard(guildID string, q leaderboardQuery) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	metric := findLeaderboardMetric(q.Metric)
+	if metric == nil {
+		return nil, nil, userErrorf("❌ Unknown leaderboard metric %s", q.Metric)
+	}
+
+	players, err := db.GetGuildPlayers(guildID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting tracked players: %w", err)
+	}
+	if len(players) == 0 {
+		return nil, nil, userErrorf("📋 No players are currently being tracked in this server")
+	}
+
+	queue := findQueueFilter(q.Queue)
+	if metric.Value == lpMetric && queue == nil {
+		queue = findQueueFilter("solo")
+	}
+	filter := StatsFilter{Since: time.Now().AddDate(0, 0, -q.Days)}
+	period := fmt.Sprintf("Last %d days", q.Days)
+	if queue != nil {
+		filter.QueueIDs = queue.QueueIDs
+		period = fmt.Sprintf("%s, %s", queue.Name, period)
+	}
+	for _, player := range players {
+		filter.PUUIDs = append(filter.PUUIDs, player.PUUID)
+	}
+
+	var entries []leaderboardEntry
+	if metric.Value == lpMetric {
+		entries, err = lpLeaderboard(players, filter, queue, q.MinGames)
+	} else {
+		entries, err = statsLeaderboard(players, filter, metric, q.MinGames)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🏆 %s Leaderboard (%s)", metric.Name, period),
+		Color: 0xFFD700,
+	}
+	if len(entries) == 0 {
+		embed.Description = fmt.Sprintf("No tracked player has played %d or more games in this period", q.MinGames)
+		if metric.Value == lpMetric {
+			embed.Description += ", with their rank recorded"
+		}
+		return embed, nil, nil
+	}
+
+	pages := (len(entries) + leaderboardPageSize - 1) / leaderboardPageSize
+	q.Page = max(0, min(q.Page, pages-1))
+	start := q.Page * leaderboardPageSize
+	end := min(start+leaderboardPageSize, len(entries))
+
+	var lines strings.Builder
+	for idx := start; idx < end; idx++ {
+		entry := &entries[idx]
+		lines.WriteString(fmt.Sprintf("%s **%s#%s** - %s\n", leaderboardPlace(idx+1),
+			entry.Player.GameName, entry.Player.TagLine, entry.Text))
+	}
+	embed.Description = lines.String()
+	embed.Footer = &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("Page %d/%d • Players with at least %d games", q.Page+1, pages, q.MinGames),
+	}
+
+	if pages == 1 {
+		return embed, nil, nil
+	}
+	previous, next := q, q
+	previous.Page--
+	next.Page++
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "◀ Previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: previous.customID(),
+					Disabled: q.Page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next ▶",
+					Style:    discordgo.SecondaryButton,
+					CustomID: next.customID(),
+					Disabled: q.Page == pages-1,
+				},
+			},
+		},
+	}
+	return embed, components, nil
+}
+
+// leaderboardPlace renders a 1-based place, with medals for the podium.
+func leaderboardPlace(place int) string {
+	switch place {
+	case 1:
+		return "🥇"
+	case 2:
+		return "🥈"
+	case 3:
+		return "🥉"
+	}
+	return fmt.Sprintf("`%d.`", place)
+}
+
+// statsLeaderboard ranks players by metric over their matches in filter.
+func statsLeaderboard(players []TrackedPlayer, filter StatsFilter, metric *leaderboardMetric, minGames int) ([]leaderboardEntry, error) {
+	groups, err := db.GetStatGroups(filter, GroupByPlayer, 0)
+	if err != nil {
+		return nil, fmt.Errorf("getting stats: %w", err)
+	}
+
+	byPUUID := make(map[string]TrackedPlayer, len(players))
+	for _, player := range players {
+		byPUUID[player.PUUID] = player
+	}
+
+	var entries []leaderboardEntry
+	for idx := range groups {
+		group := &groups[idx]
+		player, ok := byPUUID[group.Key]
+		if !ok || group.Games < minGames {
+			continue
+		}
+		entries = append(entries, leaderboardEntry{
+			Player: player,
+			Score:  metric.score(group),
+			Games:  group.Games,
+			Text:   metric.format(group),
+		})
+	}
+	sortLeaderboard(entries)
+	return entries, nil
+}
+
+// lpLeaderboard ranks players by the LP they gained in queue since
+// filter.Since: their latest rank snapshot against the last one from before
+// the window, or the first one in it for players whose tracking started
+// later.
+func lpLeaderboard(players []TrackedPlayer, filter StatsFilter, queue *queueFilter, minGames int) ([]leaderboardEntry, error) {
+	if len(queue.QueueIDs) != 1 || rankedQueueTypes[queue.QueueIDs[0]] == "" {
+		return nil, userErrorf("❌ LP is tracked per queue, pick Ranked Solo/Duo or Ranked Flex")
+	}
+	queueType := rankedQueueTypes[queue.QueueIDs[0]]
+
+	groups, err := db.GetStatGroups(filter, GroupByPlayer, 0)
+	if err != nil {
+		return nil, fmt.Errorf("getting stats: %w", err)
+	}
+	games := make(map[string]int, len(groups))
+	for _, group := range groups {
+		games[group.Key] = group.Games
+	}
+
+	var entries []leaderboardEntry
+	for _, player := range players {
+		if games[player.PUUID] < minGames {
+			continue
+		}
+
+		latest, err := db.GetRankSnapshotBefore(player.PUUID, queueType, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("getting rank of %s#%s: %w", player.GameName, player.TagLine, err)
+		}
+		baseline, err := db.GetRankSnapshotBefore(player.PUUID, queueType, filter.Since)
+		if err == nil && baseline == nil {
+			baseline, err = db.GetRankSnapshotAfter(player.PUUID, queueType, filter.Since)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting rank of %s#%s: %w", player.GameName, player.TagLine, err)
+		}
+		if latest == nil || baseline == nil {
+			continue
+		}
+
+		gained := ladderPoints(latest.Tier, latest.Rank, latest.LeaguePoints) -
+			ladderPoints(baseline.Tier, baseline.Rank, baseline.LeaguePoints)
+		entries = append(entries, leaderboardEntry{
+			Player: player,
+			Score:  float64(gained),
+			Games:  games[player.PUUID],
+			Text:   fmt.Sprintf("%+d LP (%s)", gained, formatRank(latest.leagueEntry())),
+		})
+	}
+	sortLeaderboard(entries)
+	return entries, nil
+}
+
+// sortLeaderboard orders entries by score, then games played, then name.
+func sortLeaderboard(entries []leaderboardEntry) {
+	sort.SliceStable(entries, func(a, b int) bool {
+		if entries[a].Score != entries[b].Score {
+			return entries[a].Score > entries[b].Score
+		}
+		if entries[a].Games != entries[b].Games {
+			return entries[a].Games > entries[b].Games
+		}
+		return strings.ToLower(entries[a].Player.GameName) < strings.ToLower(entries[b].Player.GameName)
+	})
+}
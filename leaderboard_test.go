@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestLeaderboardQueryCustomID(t *testing.T) {
+	tests := []struct {
+		name     string
+		customID string
+		want     leaderboardQuery
+		wantErr  bool
+	}{
+		{
+			name:     "round trip",
+			customID: leaderboardQuery{Metric: "kda", Days: 30, Queue: "solo", MinGames: 5, Page: 2}.customID(),
+			want:     leaderboardQuery{Metric: "kda", Days: 30, Queue: "solo", MinGames: 5, Page: 2},
+		},
+		{
+			name:     "round trip without queue",
+			customID: leaderboardQuery{Metric: lpMetric, Days: 7, MinGames: 1}.customID(),
+			want:     leaderboardQuery{Metric: lpMetric, Days: 7, MinGames: 1},
+		},
+		{name: "too few parts", customID: "leaderboard:kda:30:solo:5", wantErr: true},
+		{name: "too many parts", customID: "leaderboard:kda:30:solo:5:2:1", wantErr: true},
+		{name: "other component", customID: "stats:kda:30:solo:5:2", wantErr: true},
+		{name: "non-numeric days", customID: "leaderboard:kda:week:solo:5:2", wantErr: true},
+		{name: "non-numeric min games", customID: "leaderboard:kda:30:solo:five:2", wantErr: true},
+		{name: "non-numeric page", customID: "leaderboard:kda:30:solo:5:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLeaderboardQuery(tt.customID)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseLeaderboardQuery(%q) = %+v, %v; want %+v (error: %v)",
+					tt.customID, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
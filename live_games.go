@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// liveGameTTL is how long a live message is kept waiting for its match to
+// show up in match-v5 before it is forgotten.
+const liveGameTTL = 24 * time.Hour
+
+const (
+	blueTeamID = 100
+	redTeamID  = 200
+)
+
+// announceLiveGame posts an "in game now" embed for player's active game to
+// every guild that tracks them, once per game.
+func (gm *GameMonitor) announceLiveGame(ctx context.Context, player TrackedPlayer, game *ActiveGame) error {
+	gameID := strconv.FormatInt(game.GameID, 10)
+	existing, err := gm.db.GetLiveGames(player.PUUID, gameID)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	channels, err := gm.db.GetPlayerChannels(player.PUUID)
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	embed := gm.buildLiveGameEmbed(ctx, player, game)
+	for _, channelID := range channels {
+		message, err := gm.discord.ChannelMessageSendEmbed(channelID, embed)
+		if err != nil {
+			discordSendFailures.Inc()
+			log.Printf("Error sending live game to %s: %v", channelID, err)
+			continue
+		}
+
+		err = gm.db.AddLiveGame(&LiveGame{
+			PUUID:     player.PUUID,
+			GameID:    gameID,
+			ChannelID: message.ChannelID,
+			MessageID: message.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gm *GameMonitor) buildLiveGameEmbed(ctx context.Context, player TrackedPlayer, game *ActiveGame) *discordgo.MessageEmbed {
+	var champion string
+	var blue, red strings.Builder
+	for _, participant := range game.Participants {
+		name := gm.riotAPI.ChampionName(ctx, participant.ChampionID)
+		line := fmt.Sprintf("%s - %s • %s\n", name, participant.RiotID, gm.participantRank(ctx, player.Platform, participant.PUUID, participant.Bot))
+		if participant.PUUID == player.PUUID {
+			champion = name
+			line = "▶ **" + strings.TrimSuffix(line, "\n") + "**\n"
+		}
+
+		if participant.TeamID == redTeamID {
+			red.WriteString(line)
+		} else {
+			blue.WriteString(line)
+		}
+	}
+
+	started := "just now"
+	if game.GameStartTime > 0 {
+		started = fmt.Sprintf("<t:%d:R>", game.GameStartTime/1000)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🔴 Live - %s#%s is in game", player.GameName, player.TagLine),
+		Color: 0x9B59B6,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Champion",
+				Value:  nonEmpty(champion),
+				Inline: true,
+			},
+			{
+				Name:   "Queue",
+				Value:  liveQueueName(game),
+				Inline: true,
+			},
+			{
+				Name:   "Started",
+				Value:  started,
+				Inline: true,
+			},
+			{
+				Name:  "🔵 Blue Team",
+				Value: nonEmpty(blue.String()),
+			},
+			{
+				Name:  "🔴 Red Team",
+				Value: nonEmpty(red.String()),
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "This message will be updated when the game ends",
+		},
+	}
+}
+
+// participantRank looks up a participant's rank for the live embed. Failures
+// are shown inline rather than holding up the announcement.
+func (gm *GameMonitor) participantRank(ctx context.Context, platform, puuid string, bot bool) string {
+	if bot || puuid == "" {
+		return "Bot"
+	}
+	entries, err := gm.riotAPI.GetLeagueEntries(ctx, platform, puuid)
+	if err != nil {
+		log.Printf("Error getting rank for live game participant: %v", err)
+		return "?"
+	}
+	return formatRank(primaryLeagueEntry(entries))
+}
+
+// nonEmpty keeps embed field values valid; Discord rejects empty ones.
+func nonEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -15,66 +19,109 @@ import (
 )
 
 var (
-	db          *Database
+	db          Store
 	riotAPI     *RiotAPI
+	alerter     *AdminAlerter
+	keyManager  *KeyManager
 	gameMonitor *GameMonitor
+	backfiller  *Backfiller
+
+	adminUserID string
+	adminRoleID string
 )
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrateCommand(os.Args[2:])
+			return
+		case "rederive":
+			runRederiveCommand()
+			return
+		}
+	}
+
 	token := os.Getenv("DISCORD_TOKEN")
 	if token == "" {
 		log.Fatal("DISCORD_TOKEN environment variable is required")
 	}
 
+	// The key can come from the environment or from a mounted secret file
+	// that is re-read on SIGHUP and whenever it changes
 	riotAPIKey := os.Getenv("RIOT_API_KEY")
-	if riotAPIKey == "" {
-		log.Fatal("RIOT_API_KEY environment variable is required")
+	riotAPIKeyFile := os.Getenv("RIOT_API_KEY_FILE")
+	if riotAPIKey == "" && riotAPIKeyFile == "" {
+		log.Fatal("RIOT_API_KEY or RIOT_API_KEY_FILE environment variable is required")
 	}
 
 	// Initialize database
 	var err error
-	dbHost := os.Getenv("DB_HOST")
-	if dbHost == "" {
-		dbHost = "localhost"
-	}
-	dbPort := os.Getenv("DB_PORT")
-	if dbPort == "" {
-		dbPort = "5432"
-	}
-	dbUser := os.Getenv("DB_USER")
-	if dbUser == "" {
-		dbUser = "postgres"
+	db, err = openDatabase()
+	if err != nil {
+		log.Fatal("Error initializing database:", err)
 	}
-	dbPassword := os.Getenv("DB_PASSWORD")
-	if dbPassword == "" {
-		dbPassword = "postgres"
+	defer db.Close()
+
+	// Replicas starting together wait on each other here, see Migrate
+	applied, err := db.Migrate(context.Background())
+	if err != nil {
+		log.Fatal("Error migrating database:", err)
 	}
-	dbName := os.Getenv("DB_NAME")
-	if dbName == "" {
-		dbName = "lol_bot"
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
 	}
 
-	db, err = NewDatabase(dbHost, dbPort, dbUser, dbPassword, dbName)
-	if err != nil {
-		log.Fatal("Error initializing database:", err)
+	// Expose Prometheus metrics for the discord-bot scrape job
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":8080"
 	}
-	defer db.Close()
+	metricsServer := startMetricsServer(metricsAddr)
+	defer metricsServer.Close()
 
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
 		log.Fatal("Error creating Discord session:", err)
 	}
 
-	// Get monitor channel ID for the riot API
+	// Notification channels are configured per guild with /config channel;
+	// MONITOR_CHANNEL_ID only seeds its guild's setting on upgrade
 	monitorChannelID := os.Getenv("MONITOR_CHANNEL_ID")
 	
-	// Initialize Riot API client
-	riotAPI = NewRiotAPI(riotAPIKey, dg, monitorChannelID)
+	// Admin alerts go to their own channel if configured, otherwise to the
+	// monitor channel
+	adminChannelID := os.Getenv("ADMIN_CHANNEL_ID")
+	if adminChannelID == "" {
+		adminChannelID = monitorChannelID
+	}
+	adminUserID = os.Getenv("ADMIN_USER_ID")
+	adminRoleID = os.Getenv("ADMIN_ROLE_ID")
+	alerter = NewAdminAlerter(dg, adminChannelID, adminUserID, adminRoleID)
+
+	// Initialize Riot API client. Nothing tells a development key from a
+	// production key, so the environment key only expires when
+	// RIOT_API_KEY_LIFETIME is set. When it was generated is unknown, so its
+	// lifetime counts from startup.
+	keyLifetime := durationEnv("RIOT_API_KEY_LIFETIME", DevKeyLifetime)
+	riotAPI = NewRiotAPI(riotAPIKey)
+	if riotAPIKey != "" && os.Getenv("RIOT_API_KEY_LIFETIME") != "" && keyLifetime > 0 {
+		riotAPI.SetAPIKey(riotAPIKey, time.Now().Add(keyLifetime))
+	}
+	riotAPI.Hooks = alerter.Hooks()
+
+	keyManager = NewKeyManager(riotAPI, alerter, riotAPIKeyFile, keyLifetime,
+		durationEnv("RIOT_API_KEY_EXPIRY_WARNING", defaultKeyExpiryWarn))
+	keyManager.Start()
+	defer keyManager.Stop()
+
+	backfiller = NewBackfiller(db, riotAPI, dg)
 
 	dg.AddHandler(messageCreate)
-	dg.AddHandler(interactionCreate)
+	router.HandleComponent(leaderboardComponent, handleLeaderboardPage)
+	dg.AddHandler(router.Handle)
 
 	dg.Identify.Intents = discordgo.IntentsGuildMessages
 
@@ -83,19 +130,33 @@ func main() {
 		log.Fatal("Error opening connection:", err)
 	}
 
-	registerSlashCommands(dg)
+	adoptLegacyChannel(dg, monitorChannelID)
 
-	// Also register guild-specific commands for faster testing
-	guilds := dg.State.Guilds
-	for _, guild := range guilds {
-		registerGuildSlashCommands(dg, guild.ID)
+	// DISCORD_DEV_GUILD_ID registers the commands in one guild only, where
+	// changes apply instantly, instead of globally
+	if err := syncCommands(dg, os.Getenv("DISCORD_DEV_GUILD_ID")); err != nil {
+		log.Printf("Error registering slash commands: %v", err)
 	}
 
 	// Initialize and start game monitor
-	gameMonitor = NewGameMonitor(db, riotAPI, dg, monitorChannelID)
+	monitorWorkers, _ := strconv.Atoi(os.Getenv("MONITOR_WORKERS"))
+	catchUpLimit, _ := strconv.Atoi(os.Getenv("MATCH_CATCHUP_LIMIT"))
+	maxMatchAttempts, _ := strconv.Atoi(os.Getenv("MATCH_MAX_ATTEMPTS"))
+	gameMonitor = NewGameMonitor(db, riotAPI, alerter, dg, MonitorConfig{
+		PollInterval:     durationEnv("MONITOR_INTERVAL", defaultPollInterval),
+		Workers:          monitorWorkers,
+		CatchUpLimit:     catchUpLimit,
+		MaxMatchAttempts: maxMatchAttempts,
+		LiveGames:        os.Getenv("MONITOR_LIVE_GAMES") == "true",
+
+		AccountRefreshInterval: durationEnv("ACCOUNT_REFRESH_INTERVAL", defaultAccountRefreshInterval),
+	})
 	gameMonitor.Start()
 	defer gameMonitor.Stop()
 
+	backfiller.Start()
+	defer backfiller.Stop()
+
 	log.Println("Bot is now running. Press CTRL-C to exit.")
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
@@ -104,6 +165,122 @@ func main() {
 	dg.Close()
 }
 
+// openDatabase opens the database configured by the environment: DB_DRIVER
+// picks PostgreSQL (the default, DB_HOST etc.) or SQLite (DB_PATH).
+func openDatabase() (Store, error) {
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", "postgres":
+	case "sqlite":
+		dbPath := os.Getenv("DB_PATH")
+		if dbPath == "" {
+			dbPath = "lol_bot.db"
+		}
+		return NewSQLiteStore(dbPath)
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q, expected postgres or sqlite", driver)
+	}
+
+	dbHost := os.Getenv("DB_HOST")
+	if dbHost == "" {
+		dbHost = "localhost"
+	}
+	dbPort := os.Getenv("DB_PORT")
+	if dbPort == "" {
+		dbPort = "5432"
+	}
+	dbUser := os.Getenv("DB_USER")
+	if dbUser == "" {
+		dbUser = "postgres"
+	}
+	dbPassword := os.Getenv("DB_PASSWORD")
+	if dbPassword == "" {
+		dbPassword = "postgres"
+	}
+	dbName := os.Getenv("DB_NAME")
+	if dbName == "" {
+		dbName = "lol_bot"
+	}
+
+	return NewPostgresStore(dbHost, dbPort, dbUser, dbPassword, dbName)
+}
+
+// runMigrateCommand implements "discord-bot migrate [up|status]", which
+// applies or lists schema migrations without starting the bot.
+func runMigrateCommand(args []string) {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	if action != "up" && action != "status" {
+		fmt.Fprintln(os.Stderr, "usage: discord-bot migrate [up|status]")
+		os.Exit(2)
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		log.Fatal("Error initializing database:", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if action == "status" {
+		statuses, err := database.Migrations(ctx)
+		if err != nil {
+			log.Fatal("Error reading migrations:", err)
+		}
+		for _, m := range statuses {
+			applied := "pending"
+			if !m.AppliedAt.IsZero() {
+				applied = "applied " + m.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, applied)
+		}
+		return
+	}
+
+	applied, err := database.Migrate(ctx)
+	if err != nil {
+		log.Fatal("Error migrating database:", err)
+	}
+	for _, m := range applied {
+		fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+	}
+	if len(applied) == 0 {
+		fmt.Println("Database is up to date")
+	}
+}
+
+// runRederiveCommand implements "discord-bot rederive", which rebuilds the
+// per-player match tables from the stored match payloads.
+func runRederiveCommand() {
+	database, err := openDatabase()
+	if err != nil {
+		log.Fatal("Error initializing database:", err)
+	}
+	defer database.Close()
+
+	rebuilt, err := rederiveMatches(database)
+	if err != nil {
+		log.Fatalf("Error rederiving matches after %d matches: %v", rebuilt, err)
+	}
+	fmt.Printf("Rederived %d matches\n", rebuilt)
+}
+
+// durationEnv parses a Go duration (e.g. "24h") from the environment, falling
+// back to def when unset or invalid.
+func durationEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s: %v", name, value, def, err)
+		return def
+	}
+	return d
+}
+
 func getLatestPatchNotesURL() string {
 	// Try to get the latest patch notes URL
 	resp, err := http.Get("https://www.leagueoflegends.com/en-us/news/tags/patch-notes/")
@@ -118,6 +295,52 @@ func getLatestPatchNotesURL() string {
 	return "https://www.leagueoflegends.com/en-us/news/tags/patch-notes/"
 }
 
+// adminPermissions hides admin commands from members without Administrator
+// by default; the command's Permission still checks isBotAdmin or
+// isGuildAdmin since guilds can override this.
+var adminPermissions int64 = discordgo.PermissionAdministrator
+
+// isBotAdmin reports whether the invoking user may run commands that act on
+// the whole bot, e.g. replacing the shared Riot API key: ADMIN_USER_ID and
+// members of ADMIN_ROLE_ID. Role IDs are unique across Discord, so the role
+// ties this to the bot owner's server. Guild administrators do not count;
+// any server can invite the bot.
+func isBotAdmin(i *discordgo.InteractionCreate) bool {
+	if adminUserID != "" && interactionUserID(i) == adminUserID {
+		return true
+	}
+	if adminRoleID == "" || i.Member == nil {
+		return false
+	}
+	for _, roleID := range i.Member.Roles {
+		if roleID == adminRoleID {
+			return true
+		}
+	}
+	return false
+}
+
+// isGuildAdmin reports whether the invoking member may run admin commands
+// that only affect their own server: its administrators and bot admins.
+func isGuildAdmin(i *discordgo.InteractionCreate) bool {
+	if isBotAdmin(i) {
+		return true
+	}
+	return i.Member != nil && i.Member.Permissions&discordgo.PermissionAdministrator != 0
+}
+
+// interactionUserID returns the invoking user for both guild and DM
+// interactions.
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
 func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	if m.Author.ID == s.State.User.ID {
 		return
@@ -136,246 +359,51 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	}
 }
 
-func registerGuildSlashCommands(s *discordgo.Session, guildID string) {
-	commands := []*discordgo.ApplicationCommand{
-		{
-			Name:        "help",
-			Description: "Show help information",
-		},
-		{
-			Name:        "pn",
-			Description: "Get the latest League of Legends patch notes",
-		},
-		{
-			Name:        "patchnotes",
-			Description: "Get the latest League of Legends patch notes",
-		},
-		{
-			Name:        "track",
-			Description: "Track a League of Legends player",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "summoner",
-					Description: "Summoner name (e.g., PlayerName#TAG)",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "untrack",
-			Description: "Stop tracking a League of Legends player",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "summoner",
-					Description: "Summoner name (e.g., PlayerName#TAG)",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "stats",
-			Description: "Show stats for a tracked player",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "summoner",
-					Description: "Summoner name (e.g., PlayerName#TAG)",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "days",
-					Description: "Number of days to look back (default: 7)",
-					Required:    false,
-				},
-			},
-		},
-		{
-			Name:        "tracked",
-			Description: "List all tracked players",
-		},
-	}
-
-	log.Printf("Registering %d guild-specific slash commands for guild %s...", len(commands), guildID)
-	for _, cmd := range commands {
-		createdCmd, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, cmd)
-		if err != nil {
-			log.Printf("Cannot create guild command '%v': %v", cmd.Name, err)
-		} else {
-			log.Printf("Successfully created guild command: %s (ID: %s)", createdCmd.Name, createdCmd.ID)
-		}
+func handleTrackCommand(c *CommandContext) error {
+	gameName, tagLine, err := c.RiotIDOption("summoner")
+	if err != nil {
+		return err
 	}
-}
 
-func registerSlashCommands(s *discordgo.Session) {
-	commands := []*discordgo.ApplicationCommand{
-		{
-			Name:        "help",
-			Description: "Show help information",
-		},
-		{
-			Name:        "pn",
-			Description: "Get the latest League of Legends patch notes",
-		},
-		{
-			Name:        "patchnotes",
-			Description: "Get the latest League of Legends patch notes",
-		},
-		{
-			Name:        "track",
-			Description: "Track a League of Legends player",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "summoner",
-					Description: "Summoner name (e.g., PlayerName#TAG)",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "untrack",
-			Description: "Stop tracking a League of Legends player",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "summoner",
-					Description: "Summoner name (e.g., PlayerName#TAG)",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "stats",
-			Description: "Show stats for a tracked player",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "summoner",
-					Description: "Summoner name (e.g., PlayerName#TAG)",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "days",
-					Description: "Number of days to look back (default: 7)",
-					Required:    false,
-				},
-			},
-		},
-		{
-			Name:        "tracked",
-			Description: "List all tracked players",
-		},
-	}
-
-	log.Printf("Registering %d slash commands...", len(commands))
-	for _, cmd := range commands {
-		createdCmd, err := s.ApplicationCommandCreate(s.State.User.ID, "", cmd)
-		if err != nil {
-			log.Printf("Cannot create '%v' command: %v", cmd.Name, err)
-		} else {
-			log.Printf("Successfully created command: %s (ID: %s)", createdCmd.Name, createdCmd.ID)
-		}
+	region := c.StringOption("region")
+	platform, err := normalizePlatform(region)
+	if err != nil {
+		return userErrorf("❌ Unknown region %s", region)
 	}
-	log.Println("Slash commands registration completed!")
-}
-
-func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	log.Printf("Interaction received: %s", i.ApplicationCommandData().Name)
-
-	commandName := i.ApplicationCommandData().Name
-
-	switch commandName {
-	case "help":
-		helpText := `**League of Legends Bot Commands:**
-
-🎮 **Player Tracking:**
-• /track <summoner> - Track a player's games (e.g., /track PlayerName#TAG)
-• /untrack <summoner> - Stop tracking a player
-• /stats <summoner> [days] - Show player stats (default: 7 days)
-• /tracked - List all tracked players
-
-📋 **Other Commands:**
-• /pn or /patchnotes - Get latest patch notes
 
-The bot will automatically post game summaries when tracked players finish games!`
-
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: helpText,
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-	case "pn", "patchnotes":
-		log.Printf("Processing patch notes command: %s", commandName)
-		patchNotesURL := getLatestPatchNotesURL()
-		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("📋 **Latest League of Legends Patch Notes:**\n%s", patchNotesURL),
-			},
-		})
-		if err != nil {
-			log.Printf("Error responding to interaction: %v", err)
-		}
-	case "track":
-		handleTrackCommand(s, i)
-	case "untrack":
-		handleUntrackCommand(s, i)
-	case "stats":
-		handleStatsCommand(s, i)
-	case "tracked":
-		handleTrackedCommand(s, i)
+	ctx := c.Context
+	account, err := riotAPI.GetAccountByRiotID(ctx, platform, gameName, tagLine)
+	if err != nil {
+		return riotError(fmt.Sprintf("player %s#%s on %s", gameName, tagLine, strings.ToUpper(platform)), err)
 	}
-}
-
-func handleTrackCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	summonerName := i.ApplicationCommandData().Options[0].StringValue()
-	parts := strings.Split(summonerName, "#")
-	if len(parts) != 2 {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "❌ Invalid format. Please use: PlayerName#TAG",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+	// Lookups ignore case, so store the Riot ID as Riot spells it rather
+	// than as typed; the rename check would see the difference otherwise.
+	if account.GameName != "" {
+		gameName, tagLine = account.GameName, account.TagLine
 	}
 
-	gameName, tagLine := parts[0], parts[1]
-
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("🔍 Looking up player %s#%s...", gameName, tagLine),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
-	})
-
-	account, err := riotAPI.GetAccountByRiotIDWithUser(gameName, tagLine, i.Member.User.ID)
+	// Another server may already track this player; just subscribe this one
+	// so their match history cursor is left alone.
+	existing, err := db.GetTrackedPlayer(account.PUUID)
 	if err != nil {
-		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: fmt.Sprintf("❌ Error finding player %s#%s: %v", gameName, tagLine, err),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		})
-		return
+		return fmt.Errorf("looking up player in database: %w", err)
+	}
+	if existing != nil {
+		if err := db.AddGuildPlayer(c.GuildID(), account.PUUID, c.UserID()); err != nil {
+			return fmt.Errorf("adding player to database: %w", err)
+		}
+		if err := c.Respond(fmt.Sprintf("✅ Now tracking %s#%s on %s%s", gameName, tagLine, strings.ToUpper(existing.Platform), channelHint(c.GuildID()))); err != nil {
+			return err
+		}
+		return trackBackfill(c, *existing)
 	}
 
-	summoner, err := riotAPI.GetSummonerByPUUIDWithUser(account.PUUID, i.Member.User.ID)
+	summoner, err := riotAPI.GetSummonerByPUUID(ctx, platform, account.PUUID)
 	if err != nil {
-		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: fmt.Sprintf("❌ Error getting summoner data: %v", err),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		})
-		return
+		return riotError(fmt.Sprintf("summoner data for %s#%s", gameName, tagLine), err)
 	}
 
-	matchIDs, err := riotAPI.GetMatchHistory(account.PUUID, 1)
+	matchIDs, err := riotAPI.GetMatchHistory(ctx, platform, account.PUUID, 1)
 	if err != nil {
 		log.Printf("Warning: Could not get match history for initial setup: %v", err)
 	}
@@ -390,224 +418,184 @@ func handleTrackCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		GameName:    gameName,
 		TagLine:     tagLine,
 		SummonerID:  summoner.ID,
+		Platform:    platform,
 		LastMatchID: lastMatchID,
 	}
 
 	if err := db.AddTrackedPlayer(player); err != nil {
-		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: fmt.Sprintf("❌ Error adding player to database: %v", err),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		})
-		return
+		return fmt.Errorf("adding player to database: %w", err)
+	}
+	if err := db.AddGuildPlayer(c.GuildID(), player.PUUID, c.UserID()); err != nil {
+		return fmt.Errorf("adding player to database: %w", err)
 	}
 
-	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Content: fmt.Sprintf("✅ Now tracking %s#%s (Level %d)", gameName, tagLine, summoner.SummonerLevel),
-		Flags:   discordgo.MessageFlagsEphemeral,
-	})
-}
+	// Baseline for LP changes on the first ranked game we see.
+	rank := "Unranked"
+	entries, err := riotAPI.GetLeagueEntries(ctx, platform, account.PUUID)
+	if err != nil {
+		log.Printf("Warning: Could not get ranked entries for initial setup: %v", err)
+	} else {
+		rank = formatRank(primaryLeagueEntry(entries))
+		if err := recordRankSnapshots(db, account.PUUID, entries); err != nil {
+			log.Printf("Warning: Could not save initial rank snapshot: %v", err)
+		}
+	}
 
-func handleUntrackCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	summonerName := i.ApplicationCommandData().Options[0].StringValue()
-	parts := strings.Split(summonerName, "#")
-	if len(parts) != 2 {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "❌ Invalid format. Please use: PlayerName#TAG",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+	if err := c.Respond(fmt.Sprintf("✅ Now tracking %s#%s on %s (Level %d, %s)%s", gameName, tagLine, strings.ToUpper(platform), summoner.SummonerLevel, rank, channelHint(c.GuildID()))); err != nil {
+		return err
 	}
+	return trackBackfill(c, *player)
+}
 
-	gameName, tagLine := parts[0], parts[1]
+func handleUntrackCommand(c *CommandContext) error {
+	gameName, tagLine, err := c.RiotIDOption("summoner")
+	if err != nil {
+		return err
+	}
 
-	player, err := db.GetPlayerByRiotID(gameName, tagLine)
+	player, err := guildPlayerByRiotID(c.GuildID(), gameName, tagLine)
 	if err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("❌ Player %s#%s is not being tracked", gameName, tagLine),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+		return err
 	}
 
-	if err := db.RemoveTrackedPlayer(player.PUUID); err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("❌ Error removing player: %v", err),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+	if _, err := db.RemoveGuildPlayer(c.GuildID(), player.PUUID); err != nil {
+		return fmt.Errorf("removing player: %w", err)
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("✅ Stopped tracking %s#%s", gameName, tagLine),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
-	})
+	return c.Respond(fmt.Sprintf("✅ Stopped tracking %s#%s", player.GameName, player.TagLine))
 }
 
-func handleStatsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	summonerName := i.ApplicationCommandData().Options[0].StringValue()
-	parts := strings.Split(summonerName, "#")
-	if len(parts) != 2 {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "❌ Invalid format. Please use: PlayerName#TAG",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+// autocompleteTrackedPlayers suggests this server's tracked players for a
+// summoner option.
+func autocompleteTrackedPlayers(c *CommandContext) error {
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	focused := c.FocusedOption()
+	if c.GuildID() == "" || focused == nil {
+		return c.RespondChoices(choices)
 	}
 
-	days := 7
-	if len(i.ApplicationCommandData().Options) > 1 {
-		days = int(i.ApplicationCommandData().Options[1].IntValue())
+	players, err := db.SearchGuildPlayers(c.GuildID(), focused.StringValue(), maxAutocompleteChoices)
+	if err != nil {
+		// Still answer, or Discord shows the user a failure.
+		c.RespondChoices(choices)
+		return fmt.Errorf("searching tracked players: %w", err)
 	}
 
-	gameName, tagLine := parts[0], parts[1]
+	for _, player := range players {
+		riotID := player.GameName + "#" + player.TagLine
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s (%s)", riotID, strings.ToUpper(player.Platform)),
+			Value: riotID,
+		})
+	}
+	return c.RespondChoices(choices)
+}
 
-	player, err := db.GetPlayerByRiotID(gameName, tagLine)
+// guildPlayerByRiotID looks up a player tracked in guildID, with a user
+// facing error when there is none.
+func guildPlayerByRiotID(guildID, gameName, tagLine string) (*TrackedPlayer, error) {
+	player, err := db.GetGuildPlayerByRiotID(guildID, gameName, tagLine)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, userErrorf("❌ Player %s#%s is not being tracked", gameName, tagLine)
+	}
 	if err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("❌ Player %s#%s is not being tracked", gameName, tagLine),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+		return nil, fmt.Errorf("looking up player: %w", err)
 	}
+	return player, nil
+}
 
-	matches, err := db.GetPlayerStats(player.PUUID, days)
+func handleTrackedCommand(c *CommandContext) error {
+	players, err := db.GetGuildPlayers(c.GuildID())
 	if err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("❌ Error getting stats: %v", err),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+		return fmt.Errorf("getting tracked players: %w", err)
 	}
 
-	if len(matches) == 0 {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("📊 No games found for %s#%s in the last %d days", gameName, tagLine, days),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+	if len(players) == 0 {
+		return c.Respond("📋 No players are currently being tracked in this server")
+	}
+
+	var content strings.Builder
+	content.WriteString("📋 **Currently Tracked Players:**\n\n")
+	for _, player := range players {
+		content.WriteString(fmt.Sprintf("• %s#%s [%s] (Added: %s) - %s", player.GameName, player.TagLine, strings.ToUpper(player.Platform), player.CreatedAt.Format("2006-01-02"), pollStateLabel(player.PollState)))
+		if !player.NextCheckAt.IsZero() {
+			content.WriteString(fmt.Sprintf(", next check <t:%d:R>", player.NextCheckAt.Unix()))
+		}
+		content.WriteString("\n")
+	}
+
+	return c.Respond(content.String())
+}
+
+func handleAdminCommand(c *CommandContext) error {
+	switch c.Subcommand() {
+	case "setkey":
+		return handleSetKeyCommand(c)
+	case "failedmatches":
+		return handleFailedMatchesCommand(c)
+	case "retrymatch":
+		return handleRetryMatchCommand(c)
 	}
+	return nil
+}
+
+func handleSetKeyCommand(c *CommandContext) error {
+	key := c.StringOption("key")
+	expiresIn := time.Duration(c.IntOption("expires_in_hours", int(DevKeyLifetime/time.Hour))) * time.Hour
 
-	wins := 0
-	totalKills := 0
-	totalDeaths := 0
-	totalAssists := 0
-	totalCS := 0
-	totalDamage := 0
+	// Validating the key calls Riot, which can take a while.
+	if err := c.Defer(); err != nil {
+		return err
+	}
+
+	var expiresAt time.Time
+	if expiresIn > 0 {
+		expiresAt = time.Now().Add(expiresIn)
+	}
 
-	for _, match := range matches {
-		if match.Win {
-			wins++
+	if err := keyManager.SetKey(context.Background(), key, expiresAt); err != nil {
+		if errors.Is(err, ErrUnauthorized) {
+			return userErrorf("❌ Riot rejected the new key, the old key is still in use")
 		}
-		totalKills += match.Kills
-		totalDeaths += match.Deaths
-		totalAssists += match.Assists
-		totalCS += match.CreepScore
-		totalDamage += match.DamageDealt
-	}
-
-	winRate := float64(wins) / float64(len(matches)) * 100
-	avgKDA := float64(totalKills+totalAssists) / float64(max(totalDeaths, 1))
-
-	embed := &discordgo.MessageEmbed{
-		Title: fmt.Sprintf("📊 Stats for %s#%s (Last %d days)", gameName, tagLine, days),
-		Color: 0x0099FF,
-		Fields: []*discordgo.MessageEmbedField{
-			{
-				Name:   "Games Played",
-				Value:  fmt.Sprintf("%d", len(matches)),
-				Inline: true,
-			},
-			{
-				Name:   "Win Rate",
-				Value:  fmt.Sprintf("%.1f%% (%d wins)", winRate, wins),
-				Inline: true,
-			},
-			{
-				Name:   "Average KDA",
-				Value:  fmt.Sprintf("%.1f/%.1f/%.1f (%.2f)", float64(totalKills)/float64(len(matches)), float64(totalDeaths)/float64(len(matches)), float64(totalAssists)/float64(len(matches)), avgKDA),
-				Inline: true,
-			},
-			{
-				Name:   "Average CS",
-				Value:  fmt.Sprintf("%.1f", float64(totalCS)/float64(len(matches))),
-				Inline: true,
-			},
-			{
-				Name:   "Average Damage",
-				Value:  fmt.Sprintf("%,.0f", float64(totalDamage)/float64(len(matches))),
-				Inline: true,
-			},
-		},
-	}
-
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{embed},
-			Flags:  discordgo.MessageFlagsEphemeral,
-		},
-	})
+		return userErrorf("❌ Key was not changed: %v", err)
+	}
+
+	if expiresAt.IsZero() {
+		return c.Respond("✅ Riot API key updated (no expiry)")
+	}
+	return c.Respond(fmt.Sprintf("✅ Riot API key updated, expires <t:%d:R>", expiresAt.Unix()))
 }
 
-func handleTrackedCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	players, err := db.GetTrackedPlayers()
+func handleFailedMatchesCommand(c *CommandContext) error {
+	entries, err := db.GetFailedMatches(20)
 	if err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("❌ Error getting tracked players: %v", err),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+		return fmt.Errorf("getting failed matches: %w", err)
 	}
 
-	if len(players) == 0 {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "📋 No players are currently being tracked",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+	if len(entries) == 0 {
+		return c.Respond("✅ No failed matches")
 	}
 
 	var content strings.Builder
-	content.WriteString("📋 **Currently Tracked Players:**\n\n")
-	for _, player := range players {
-		content.WriteString(fmt.Sprintf("• %s#%s (Added: %s)\n", player.GameName, player.TagLine, player.CreatedAt.Format("2006-01-02")))
+	content.WriteString("⚠️ **Failed Matches:**\n\n")
+	for _, entry := range entries {
+		content.WriteString(fmt.Sprintf("• `%s` - %d attempts, last %s: %s\n",
+			entry.MatchID, entry.Attempts, entry.UpdatedAt.Format("2006-01-02 15:04"), truncate(entry.LastError, 100)))
 	}
+	content.WriteString("\nUse /admin retrymatch to try one again.")
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: content.String(),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
-	})
+	return c.Respond(content.String())
+}
+
+func handleRetryMatchCommand(c *CommandContext) error {
+	matchID := strings.TrimSpace(c.StringOption("match_id"))
+	reset, err := db.RetryFailedMatch(matchID)
+	if err != nil {
+		return fmt.Errorf("retrying match: %w", err)
+	}
+	if reset == 0 {
+		return userErrorf("❌ Match %s is not in the failed list", matchID)
+	}
+
+	return c.Respond(fmt.Sprintf("✅ Match %s will be retried on the next check", matchID))
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// loadMatch returns the match with ID matchID, from the database if it was
+// stored before (e.g. because another tracked player was in it) and from
+// match-v5 otherwise, storing it for next time.
+func loadMatch(ctx context.Context, store Store, riotAPI *RiotAPI, platform, matchID string) (*Match, error) {
+	match, err := store.GetMatch(matchID)
+	if err != nil {
+		log.Printf("Error reading stored match %s, fetching it again: %v", matchID, err)
+	}
+	if match != nil {
+		return match, nil
+	}
+
+	match, err = riotAPI.GetMatchDetails(ctx, platform, matchID)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.SaveMatch(match); err != nil {
+		return nil, fmt.Errorf("storing match: %w", err)
+	}
+	return match, nil
+}
+
+// rederiveMatches rebuilds match_participants and match_data from the
+// stored payloads, so stats added to them later cover past games too. It
+// returns the number of matches rebuilt.
+func rederiveMatches(store Store) (int, error) {
+	matchIDs, err := store.GetStoredMatchIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	for idx, matchID := range matchIDs {
+		match, err := store.GetMatch(matchID)
+		if err != nil {
+			return idx, fmt.Errorf("match %s: %w", matchID, err)
+		}
+		if match == nil {
+			continue
+		}
+		if err := store.SaveMatch(match); err != nil {
+			return idx, fmt.Errorf("match %s: %w", matchID, err)
+		}
+		for pidx := range match.Info.Participants {
+			if err := store.UpdateMatchData(match.participantData(&match.Info.Participants[pidx])); err != nil {
+				return idx, fmt.Errorf("match %s: %w", matchID, err)
+			}
+		}
+	}
+	return len(matchIDs), nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	riotRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "riot_api_requests_total",
+		Help: "Total number of Riot API requests by endpoint and HTTP status code.",
+	}, []string{"endpoint", "status"})
+
+	riotRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riot_api_request_duration_seconds",
+		Help:    "Duration of Riot API requests by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	riotRateLimitWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riot_api_rate_limit_wait_seconds",
+		Help:    "Time Riot API requests spent queued behind the rate limiter.",
+		Buckets: []float64{0.05, 0.25, 1, 5, 15, 30, 60, 120},
+	}, []string{"endpoint"})
+
+	monitorCycleDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "game_monitor_cycle_duration_seconds",
+		Help:    "Duration of a full game monitor check cycle.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600},
+	})
+
+	monitorCyclesSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_monitor_cycles_skipped_total",
+		Help: "Total number of monitor cycles skipped because the previous one was still running.",
+	})
+
+	monitorPlayersChecked = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_monitor_players_checked_total",
+		Help: "Total number of tracked players checked by the game monitor.",
+	})
+
+	monitorNewMatches = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_monitor_new_matches_total",
+		Help: "Total number of new matches found by the game monitor.",
+	})
+
+	monitorMatchesFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_monitor_matches_failed_total",
+		Help: "Total number of matches marked failed after exhausting their retries.",
+	})
+
+	backfillMatchesImported = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "backfill_matches_imported_total",
+		Help: "Total number of past matches imported by backfills.",
+	})
+
+	discordSendFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "discord_embed_send_failures_total",
+		Help: "Total number of Discord embeds that failed to send.",
+	})
+
+	slashCommandInvocations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slash_command_invocations_total",
+		Help: "Total number of slash command and component invocations by name (component:<prefix> for components).",
+	}, []string{"command"})
+
+	slashCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "slash_command_duration_seconds",
+		Help:    "Time taken to handle slash commands and components, by name.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"command"})
+
+	slashCommandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slash_command_errors_total",
+		Help: "Total number of slash commands and components that failed with an internal error, by name.",
+	}, []string{"command"})
+)
+
+// observeRiotRequest records a single Riot API call. A status of 0 means the
+// request never got a response (network error, timeout).
+func observeRiotRequest(endpoint string, status int, start time.Time) {
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	riotRequestsTotal.WithLabelValues(endpoint, statusLabel).Inc()
+	riotRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+}
+
+func startMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Metrics server listening on %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics server error: %v", err)
+		}
+	}()
+
+	return server
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// newTestStore returns a migrated SQLite store in a temporary directory.
+func newTestStore(t *testing.T) *Database {
+	t.Helper()
+
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	if _, err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return store
+}
+
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	postgres, err := loadMigrations(postgresDialect.migrations)
+	if err != nil {
+		t.Fatalf("loading postgres migrations: %v", err)
+	}
+	sqlite, err := loadMigrations(sqliteDialect.migrations)
+	if err != nil {
+		t.Fatalf("loading sqlite migrations: %v", err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("got %d postgres and %d sqlite migrations", len(postgres), len(sqlite))
+	}
+	for idx := range postgres {
+		if postgres[idx].Version != idx+1 {
+			t.Errorf("migration %d has version %d, want versions without gaps", idx, postgres[idx].Version)
+		}
+		if postgres[idx].Version != sqlite[idx].Version || postgres[idx].Name != sqlite[idx].Name {
+			t.Errorf("postgres %04d_%s does not match sqlite %04d_%s", postgres[idx].Version, postgres[idx].Name,
+				sqlite[idx].Version, sqlite[idx].Name)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	defer store.Close()
+
+	known, err := loadMigrations(sqliteDialect.migrations)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+
+	applied, err := store.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("first migrate: %v", err)
+	}
+	if len(applied) != len(known) {
+		t.Errorf("first migrate applied %d migrations, want %d", len(applied), len(known))
+	}
+
+	applied, err = store.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("second migrate applied %v, want nothing", applied)
+	}
+
+	statuses, err := store.Migrations(ctx)
+	if err != nil {
+		t.Fatalf("listing migrations: %v", err)
+	}
+	if len(statuses) != len(known) {
+		t.Fatalf("listed %d migrations, want %d", len(statuses), len(known))
+	}
+	for _, status := range statuses {
+		if status.AppliedAt.IsZero() {
+			t.Errorf("migration %04d_%s is not marked applied", status.Version, status.Name)
+		}
+	}
+}
+
+func TestRatesMigration(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	defer store.Close()
+
+	// Bring the schema to just before 0005
+	migrations, err := loadMigrations(sqliteDialect.migrations)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	conn, err := store.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("opening connection: %v", err)
+	}
+	if err := createMigrationsTable(ctx, conn); err != nil {
+		t.Fatalf("creating migrations table: %v", err)
+	}
+	for _, m := range migrations {
+		if m.Version >= 5 {
+			break
+		}
+		if _, err := store.applyMigration(ctx, conn, m); err != nil {
+			t.Fatalf("migration %04d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+	conn.Close()
+
+	// match_data.match_id is the game ID. Game 3 has no stored payload.
+	statements := []string{
+		`INSERT INTO matches (match_id, game_id, game_mode, game_duration, game_creation, payload)
+		 VALUES ('NA1_1', '1', 'CLASSIC', 1800, '2024-01-01 00:00:00', ''),
+		        ('NA1_2', '2', 'CHERRY', 1200, '2024-01-01 00:00:00', '')`,
+		`INSERT INTO match_participants (match_id, puuid, participant_id, team_id, champion_id, champion, win,
+		     kills, deaths, assists, creep_score, damage_dealt, damage_taken, vision_score, gold_earned, items)
+		 VALUES ('NA1_1', 'p', 1, 100, 1, 'Annie', 1, 5, 0, 5, 0, 0, 0, 0, 0, '[]'),
+		        ('NA1_1', 'ally', 2, 100, 1, 'Annie', 1, 15, 0, 0, 0, 0, 0, 0, 0, '[]'),
+		        ('NA1_1', 'enemy', 3, 200, 1, 'Annie', 0, 30, 0, 0, 0, 0, 0, 0, 0, '[]'),
+		        ('NA1_2', 'p', 1, 100, 1, 'Annie', 1, 5, 0, 5, 0, 0, 0, 0, 0, '[]')`,
+		`INSERT INTO match_data (match_id, puuid, champion, game_mode, game_duration, win, kills, deaths, assists,
+		     creep_score, damage_dealt, damage_taken, vision_score, gold_earned, items, game_creation)
+		 VALUES ('1', 'p', 'Annie', 'CLASSIC', 1800, 1, 5, 0, 5, 240, 0, 0, 0, 0, '[]', '2024-01-01 00:00:00'),
+		        ('2', 'p', 'Annie', 'CHERRY', 1200, 1, 5, 0, 5, 0, 0, 0, 0, 0, '[]', '2024-01-01 00:00:00'),
+		        ('3', 'p', 'Annie', 'CLASSIC', 0, 1, 5, 0, 5, 0, 0, 0, 0, 0, '[]', '2024-01-01 00:00:00')`,
+	}
+	for _, statement := range statements {
+		if _, err := store.db.Exec(statement); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+
+	applied, err := store.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	if len(applied) == 0 || applied[0].Version != 5 {
+		t.Fatalf("applied %v, want 0005 first", applied)
+	}
+
+	tests := []struct {
+		gameID      string
+		teamKills   int
+		killPart    float64
+		csPerMinute float64
+	}{
+		{gameID: "1", teamKills: 20, killPart: 0.5, csPerMinute: 8},
+		// Arena subteams are only in the payload
+		{gameID: "2"},
+		// No stored payload, no duration
+		{gameID: "3"},
+	}
+	for _, tt := range tests {
+		var teamKills int
+		var killPart, csPerMinute float64
+		err := store.db.QueryRow(`SELECT team_kills, kill_participation, cs_per_minute FROM match_data WHERE match_id = ?`,
+			tt.gameID).Scan(&teamKills, &killPart, &csPerMinute)
+		if err != nil {
+			t.Fatalf("reading game %s: %v", tt.gameID, err)
+		}
+		if teamKills != tt.teamKills || killPart != tt.killPart || csPerMinute != tt.csPerMinute {
+			t.Errorf("game %s: team kills %d, kill participation %v, CS/min %v; want %d, %v, %v",
+				tt.gameID, teamKills, killPart, csPerMinute, tt.teamKills, tt.killPart, tt.csPerMinute)
+		}
+	}
+}
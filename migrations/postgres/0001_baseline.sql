@@ -0,0 +1,124 @@
+-- Schema as of the switch to versioned migrations. Every statement is
+-- idempotent so databases created by the old startup code can apply it too.
+
+CREATE TABLE IF NOT EXISTS tracked_players (
+	id SERIAL PRIMARY KEY,
+	puuid VARCHAR(78) UNIQUE NOT NULL,
+	game_name VARCHAR(255) NOT NULL,
+	tag_line VARCHAR(16) NOT NULL,
+	summoner_id VARCHAR(63) NOT NULL,
+	platform VARCHAR(8) NOT NULL DEFAULT 'na1',
+	last_match_id VARCHAR(32),
+	last_match_at TIMESTAMP,
+	poll_state VARCHAR(16) NOT NULL DEFAULT '',
+	next_check_at TIMESTAMP,
+	account_checked_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Columns added after the first release, for tables created before them
+ALTER TABLE tracked_players ADD COLUMN IF NOT EXISTS platform VARCHAR(8) NOT NULL DEFAULT 'na1';
+ALTER TABLE tracked_players ADD COLUMN IF NOT EXISTS last_match_at TIMESTAMP;
+ALTER TABLE tracked_players ADD COLUMN IF NOT EXISTS poll_state VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE tracked_players ADD COLUMN IF NOT EXISTS next_check_at TIMESTAMP;
+ALTER TABLE tracked_players ADD COLUMN IF NOT EXISTS account_checked_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS match_data (
+	id SERIAL PRIMARY KEY,
+	match_id VARCHAR(32) NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	champion VARCHAR(50) NOT NULL,
+	game_mode VARCHAR(50) NOT NULL,
+	queue_id INTEGER NOT NULL DEFAULT 0,
+	game_duration INTEGER NOT NULL,
+	win BOOLEAN NOT NULL,
+	kills INTEGER NOT NULL,
+	deaths INTEGER NOT NULL,
+	assists INTEGER NOT NULL,
+	creep_score INTEGER NOT NULL,
+	damage_dealt INTEGER NOT NULL,
+	damage_taken INTEGER NOT NULL,
+	vision_score INTEGER NOT NULL,
+	gold_earned INTEGER NOT NULL,
+	items TEXT NOT NULL,
+	game_creation TIMESTAMP NOT NULL,
+	extracted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(match_id, puuid)
+);
+
+ALTER TABLE match_data ADD COLUMN IF NOT EXISTS queue_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS match_queue (
+	id SERIAL PRIMARY KEY,
+	puuid VARCHAR(78) NOT NULL,
+	match_id VARCHAR(32) NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(puuid, match_id)
+);
+
+CREATE TABLE IF NOT EXISTS live_games (
+	puuid VARCHAR(78) NOT NULL,
+	game_id VARCHAR(32) NOT NULL,
+	channel_id VARCHAR(32) NOT NULL,
+	message_id VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (puuid, game_id, channel_id)
+);
+
+-- Live games used to be keyed by game only, back when there was a single
+-- notification channel.
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.key_column_usage
+		WHERE table_name = 'live_games' AND constraint_name = 'live_games_pkey' AND column_name = 'channel_id'
+	) THEN
+		ALTER TABLE live_games DROP CONSTRAINT IF EXISTS live_games_pkey;
+		ALTER TABLE live_games ADD PRIMARY KEY (puuid, game_id, channel_id);
+	END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS rank_snapshots (
+	id SERIAL PRIMARY KEY,
+	puuid VARCHAR(78) NOT NULL,
+	queue_type VARCHAR(32) NOT NULL,
+	tier VARCHAR(16) NOT NULL,
+	rank VARCHAR(4) NOT NULL,
+	league_points INTEGER NOT NULL,
+	wins INTEGER NOT NULL,
+	losses INTEGER NOT NULL,
+	match_id VARCHAR(32) NOT NULL DEFAULT '',
+	lp_change INTEGER,
+	captured_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS rank_snapshots_player_idx ON rank_snapshots (puuid, queue_type, captured_at);
+
+CREATE TABLE IF NOT EXISTS guild_settings (
+	guild_id VARCHAR(32) PRIMARY KEY,
+	channel_id VARCHAR(32) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS guild_players (
+	guild_id VARCHAR(32) NOT NULL,
+	puuid VARCHAR(78) NOT NULL REFERENCES tracked_players (puuid) ON DELETE CASCADE,
+	added_by VARCHAR(32) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (guild_id, puuid)
+);
+CREATE INDEX IF NOT EXISTS guild_players_puuid_idx ON guild_players (puuid);
+
+CREATE TABLE IF NOT EXISTS player_name_history (
+	id SERIAL PRIMARY KEY,
+	puuid VARCHAR(78) NOT NULL,
+	game_name VARCHAR(255) NOT NULL,
+	tag_line VARCHAR(16) NOT NULL,
+	changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS player_name_history_puuid_idx ON player_name_history (puuid);
@@ -0,0 +1,39 @@
+-- Full match-v5 payloads, so stats can be rederived without asking Riot
+-- again. JSONB values this size are compressed by TOAST.
+CREATE TABLE matches (
+	match_id VARCHAR(32) PRIMARY KEY,
+	game_id VARCHAR(32) NOT NULL,
+	platform VARCHAR(8) NOT NULL DEFAULT '',
+	queue_id INTEGER NOT NULL DEFAULT 0,
+	game_mode VARCHAR(50) NOT NULL,
+	game_duration INTEGER NOT NULL,
+	game_creation TIMESTAMP NOT NULL,
+	payload JSONB NOT NULL,
+	fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX matches_game_id_idx ON matches (game_id);
+
+-- Every participant of a stored match, tracked or not.
+CREATE TABLE match_participants (
+	match_id VARCHAR(32) NOT NULL REFERENCES matches (match_id) ON DELETE CASCADE,
+	puuid VARCHAR(78) NOT NULL,
+	participant_id INTEGER NOT NULL,
+	team_id INTEGER NOT NULL,
+	team_position VARCHAR(16) NOT NULL DEFAULT '',
+	riot_id_game_name VARCHAR(255) NOT NULL DEFAULT '',
+	riot_id_tagline VARCHAR(16) NOT NULL DEFAULT '',
+	champion_id INTEGER NOT NULL,
+	champion VARCHAR(50) NOT NULL,
+	win BOOLEAN NOT NULL,
+	kills INTEGER NOT NULL,
+	deaths INTEGER NOT NULL,
+	assists INTEGER NOT NULL,
+	creep_score INTEGER NOT NULL,
+	damage_dealt INTEGER NOT NULL,
+	damage_taken INTEGER NOT NULL,
+	vision_score INTEGER NOT NULL,
+	gold_earned INTEGER NOT NULL,
+	items TEXT NOT NULL,
+	PRIMARY KEY (match_id, puuid)
+);
+CREATE INDEX match_participants_puuid_idx ON match_participants (puuid);
@@ -0,0 +1,17 @@
+-- Background imports of tracked players' past matches, see backfill.go.
+CREATE TABLE backfill_jobs (
+	id SERIAL PRIMARY KEY,
+	puuid VARCHAR(78) NOT NULL REFERENCES tracked_players (puuid) ON DELETE CASCADE,
+	since TIMESTAMP,
+	max_games INTEGER NOT NULL,
+	next_index INTEGER NOT NULL DEFAULT 0,
+	stored INTEGER NOT NULL DEFAULT 0,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	last_error TEXT NOT NULL DEFAULT '',
+	app_id VARCHAR(32) NOT NULL DEFAULT '',
+	interaction_token TEXT NOT NULL DEFAULT '',
+	message_id VARCHAR(32) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX backfill_jobs_status_idx ON backfill_jobs (status, id);
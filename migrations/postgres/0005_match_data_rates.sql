@@ -0,0 +1,32 @@
+-- Per-minute rates and kill participation, so short and long games compare
+-- fairly. Existing games get their team's kills from the stored participants.
+-- Arena pairs share a team ID with other pairs, so Arena games are left to
+-- `rederive`, which reads the subteams from the payload. Rows from before
+-- match payloads were stored stay unknown.
+ALTER TABLE match_data ADD COLUMN team_kills INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_data ADD COLUMN cs_per_minute DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE match_data ADD COLUMN damage_per_minute DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE match_data ADD COLUMN gold_per_minute DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE match_data ADD COLUMN vision_per_minute DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE match_data ADD COLUMN kill_participation DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+UPDATE match_data SET
+	cs_per_minute = creep_score * 60.0 / game_duration,
+	damage_per_minute = damage_dealt * 60.0 / game_duration,
+	gold_per_minute = gold_earned * 60.0 / game_duration,
+	vision_per_minute = vision_score * 60.0 / game_duration
+WHERE game_duration > 0;
+
+UPDATE match_data SET team_kills = team.kills
+FROM (
+	SELECT m.game_id, me.puuid, SUM(mp.kills) AS kills
+	FROM matches m
+	JOIN match_participants me ON me.match_id = m.match_id
+	JOIN match_participants mp ON mp.match_id = me.match_id AND mp.team_id = me.team_id
+	WHERE m.game_mode <> 'CHERRY'
+	GROUP BY m.game_id, me.puuid
+) team
+WHERE team.game_id = match_data.match_id AND team.puuid = match_data.puuid;
+
+UPDATE match_data SET kill_participation = (kills + assists) * 1.0 / team_kills
+WHERE team_kills > 0;
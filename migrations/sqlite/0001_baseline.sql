@@ -0,0 +1,103 @@
+-- SQLite version of the PostgreSQL schema at the same migration. Keep the
+-- two directories in step: every postgres migration gets a sqlite one with
+-- the same version.
+
+CREATE TABLE tracked_players (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	puuid VARCHAR(78) UNIQUE NOT NULL,
+	game_name VARCHAR(255) NOT NULL,
+	tag_line VARCHAR(16) NOT NULL,
+	summoner_id VARCHAR(63) NOT NULL,
+	platform VARCHAR(8) NOT NULL DEFAULT 'na1',
+	last_match_id VARCHAR(32),
+	last_match_at TIMESTAMP,
+	poll_state VARCHAR(16) NOT NULL DEFAULT '',
+	next_check_at TIMESTAMP,
+	account_checked_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE match_data (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	match_id VARCHAR(32) NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	champion VARCHAR(50) NOT NULL,
+	game_mode VARCHAR(50) NOT NULL,
+	queue_id INTEGER NOT NULL DEFAULT 0,
+	game_duration INTEGER NOT NULL,
+	win BOOLEAN NOT NULL,
+	kills INTEGER NOT NULL,
+	deaths INTEGER NOT NULL,
+	assists INTEGER NOT NULL,
+	creep_score INTEGER NOT NULL,
+	damage_dealt INTEGER NOT NULL,
+	damage_taken INTEGER NOT NULL,
+	vision_score INTEGER NOT NULL,
+	gold_earned INTEGER NOT NULL,
+	items TEXT NOT NULL,
+	game_creation TIMESTAMP NOT NULL,
+	extracted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(match_id, puuid)
+);
+
+CREATE TABLE match_queue (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	puuid VARCHAR(78) NOT NULL,
+	match_id VARCHAR(32) NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(puuid, match_id)
+);
+
+CREATE TABLE live_games (
+	puuid VARCHAR(78) NOT NULL,
+	game_id VARCHAR(32) NOT NULL,
+	channel_id VARCHAR(32) NOT NULL,
+	message_id VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (puuid, game_id, channel_id)
+);
+
+CREATE TABLE rank_snapshots (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	puuid VARCHAR(78) NOT NULL,
+	queue_type VARCHAR(32) NOT NULL,
+	tier VARCHAR(16) NOT NULL,
+	rank VARCHAR(4) NOT NULL,
+	league_points INTEGER NOT NULL,
+	wins INTEGER NOT NULL,
+	losses INTEGER NOT NULL,
+	match_id VARCHAR(32) NOT NULL DEFAULT '',
+	lp_change INTEGER,
+	captured_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX rank_snapshots_player_idx ON rank_snapshots (puuid, queue_type, captured_at);
+
+CREATE TABLE guild_settings (
+	guild_id VARCHAR(32) PRIMARY KEY,
+	channel_id VARCHAR(32) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE guild_players (
+	guild_id VARCHAR(32) NOT NULL,
+	puuid VARCHAR(78) NOT NULL REFERENCES tracked_players (puuid) ON DELETE CASCADE,
+	added_by VARCHAR(32) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (guild_id, puuid)
+);
+CREATE INDEX guild_players_puuid_idx ON guild_players (puuid);
+
+CREATE TABLE player_name_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	puuid VARCHAR(78) NOT NULL,
+	game_name VARCHAR(255) NOT NULL,
+	tag_line VARCHAR(16) NOT NULL,
+	changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX player_name_history_puuid_idx ON player_name_history (puuid);
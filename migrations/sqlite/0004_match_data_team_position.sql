@@ -0,0 +1,8 @@
+-- Role (match-v5 teamPosition) for /stats by role, derived like the rest of
+-- match_data. Rows from before match payloads were stored stay empty.
+ALTER TABLE match_data ADD COLUMN team_position VARCHAR(16) NOT NULL DEFAULT '';
+
+UPDATE match_data SET team_position = mp.team_position
+FROM matches m
+JOIN match_participants mp ON mp.match_id = m.match_id
+WHERE m.game_id = match_data.match_id AND mp.puuid = match_data.puuid;
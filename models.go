@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"time"
 )
 
@@ -11,74 +10,189 @@ type TrackedPlayer struct {
 	GameName    string    `db:"game_name"`
 	TagLine     string    `db:"tag_line"`
 	SummonerID  string    `db:"summoner_id"`
+	Platform    string    `db:"platform"`
 	LastMatchID string    `db:"last_match_id"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
+	LastMatchAt time.Time `db:"last_match_at"` // zero if unknown
+	PollState   string    `db:"poll_state"`    // empty until first check
+	NextCheckAt time.Time `db:"next_check_at"` // zero means due now
+	// AccountCheckedAt is when the Riot ID was last refreshed from
+	// account-v1; zero if never.
+	AccountCheckedAt time.Time `db:"account_checked_at"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
 }
 
 type MatchData struct {
-	ID             int       `db:"id"`
-	MatchID        string    `db:"match_id"`
-	PUUID          string    `db:"puuid"`
-	Champion       string    `db:"champion"`
-	GameMode       string    `db:"game_mode"`
-	GameDuration   int       `db:"game_duration"`
-	Win            bool      `db:"win"`
-	Kills          int       `db:"kills"`
-	Deaths         int       `db:"deaths"`
-	Assists        int       `db:"assists"`
-	CreepScore     int       `db:"creep_score"`
-	DamageDealt    int       `db:"damage_dealt"`
-	DamageTaken    int       `db:"damage_taken"`
-	VisionScore    int       `db:"vision_score"`
-	GoldEarned     int       `db:"gold_earned"`
-	Items          string    `db:"items"` // JSON string
-	GameCreation   time.Time `db:"game_creation"`
-	ExtractedAt    time.Time `db:"extracted_at"`
+	ID           int       `db:"id"`
+	MatchID      string    `db:"match_id"`
+	PUUID        string    `db:"puuid"`
+	Champion     string    `db:"champion"`
+	TeamPosition string    `db:"team_position"` // match-v5 teamPosition, empty outside Summoner's Rift
+	GameMode     string    `db:"game_mode"`
+	QueueID      int       `db:"queue_id"`
+	GameDuration int       `db:"game_duration"`
+	Win          bool      `db:"win"`
+	Kills        int       `db:"kills"`
+	Deaths       int       `db:"deaths"`
+	Assists      int       `db:"assists"`
+	CreepScore   int       `db:"creep_score"`
+	DamageDealt  int       `db:"damage_dealt"`
+	DamageTaken  int       `db:"damage_taken"`
+	VisionScore  int       `db:"vision_score"`
+	GoldEarned   int       `db:"gold_earned"`
+	Items        string    `db:"items"` // JSON string
+	GameCreation time.Time `db:"game_creation"`
+	ExtractedAt  time.Time `db:"extracted_at"`
+
+	// Rates derived from the totals above, see setRates. KillParticipation
+	// is a fraction of TeamKills, which is zero if unknown.
+	TeamKills         int     `db:"team_kills"`
+	CSPerMinute       float64 `db:"cs_per_minute"`
+	DamagePerMinute   float64 `db:"damage_per_minute"`
+	GoldPerMinute     float64 `db:"gold_per_minute"`
+	VisionPerMinute   float64 `db:"vision_per_minute"`
+	KillParticipation float64 `db:"kill_participation"`
+}
+
+// StatsFilter selects the match_data rows statistics are computed over.
+type StatsFilter struct {
+	PUUIDs   []string // empty means every player
+	Since    time.Time
+	QueueIDs []int // empty means every queue
+}
+
+// StatsGrouping is the match_data column stats are grouped by.
+type StatsGrouping string
+
+const (
+	StatsTotal      StatsGrouping = ""
+	GroupByChampion StatsGrouping = "champion"
+	GroupByRole     StatsGrouping = "team_position"
+	GroupByQueue    StatsGrouping = "queue_id"
+	GroupByPlayer   StatsGrouping = "puuid"
+)
+
+// StatGroup sums the matches sharing a grouping's Key, and averages their
+// rates. GameMode is set for queue groups, whose queue ID may be 0 in old
+// rows. KillParticipation only averages the KillParticipationGames whose
+// team kills are known.
+type StatGroup struct {
+	Key         string
+	GameMode    string
+	Games       int
+	Wins        int
+	Kills       int
+	Deaths      int
+	Assists     int
+	CreepScore  int
+	DamageDealt int
+	GoldEarned  int
+	VisionScore int
+
+	CSPerMinute       float64
+	DamagePerMinute   float64
+	GoldPerMinute     float64
+	VisionPerMinute   float64
+	KillParticipation float64
+
+	KillParticipationGames int
+}
+
+// Match queue statuses. A match stays pending (with a growing backoff) until
+// it is processed or has failed too many times.
+const (
+	MatchStatusPending   = "pending"
+	MatchStatusProcessed = "processed"
+	MatchStatusFailed    = "failed"
+)
+
+// QueuedMatch tracks processing of one new match for one tracked player.
+type QueuedMatch struct {
+	ID            int       `db:"id"`
+	PUUID         string    `db:"puuid"`
+	MatchID       string    `db:"match_id"`
+	Status        string    `db:"status"`
+	Attempts      int       `db:"attempts"`
+	LastError     string    `db:"last_error"`
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
 }
 
-func initDB(db *sql.DB) error {
-	createPlayersTable := `
-	CREATE TABLE IF NOT EXISTS tracked_players (
-		id SERIAL PRIMARY KEY,
-		puuid VARCHAR(78) UNIQUE NOT NULL,
-		game_name VARCHAR(255) NOT NULL,
-		tag_line VARCHAR(16) NOT NULL,
-		summoner_id VARCHAR(63) NOT NULL,
-		last_match_id VARCHAR(32),
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	createMatchesTable := `
-	CREATE TABLE IF NOT EXISTS match_data (
-		id SERIAL PRIMARY KEY,
-		match_id VARCHAR(32) NOT NULL,
-		puuid VARCHAR(78) NOT NULL,
-		champion VARCHAR(50) NOT NULL,
-		game_mode VARCHAR(50) NOT NULL,
-		game_duration INTEGER NOT NULL,
-		win BOOLEAN NOT NULL,
-		kills INTEGER NOT NULL,
-		deaths INTEGER NOT NULL,
-		assists INTEGER NOT NULL,
-		creep_score INTEGER NOT NULL,
-		damage_dealt INTEGER NOT NULL,
-		damage_taken INTEGER NOT NULL,
-		vision_score INTEGER NOT NULL,
-		gold_earned INTEGER NOT NULL,
-		items TEXT NOT NULL,
-		game_creation TIMESTAMP NOT NULL,
-		extracted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(match_id, puuid)
-	);`
-
-	if _, err := db.Exec(createPlayersTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createMatchesTable); err != nil {
-		return err
-	}
-
-	return nil
-}
\ No newline at end of file
+// Done reports whether the match needs no further work.
+func (q *QueuedMatch) Done() bool {
+	return q.Status == MatchStatusProcessed || q.Status == MatchStatusFailed
+}
+
+// NameChange is a Riot ID a player used before, recorded when the monitor
+// notices a rename.
+type NameChange struct {
+	ID        int       `db:"id"`
+	PUUID     string    `db:"puuid"`
+	GameName  string    `db:"game_name"`
+	TagLine   string    `db:"tag_line"`
+	ChangedAt time.Time `db:"changed_at"`
+}
+
+// GuildSettings holds one Discord server's configuration.
+type GuildSettings struct {
+	GuildID   string    `db:"guild_id"`
+	ChannelID string    `db:"channel_id"` // empty until /config channel is used
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// LiveGame is an "in game now" message posted for a tracked player, kept so
+// the same message can be edited into the post-game summary.
+type LiveGame struct {
+	PUUID     string    `db:"puuid"`
+	GameID    string    `db:"game_id"`
+	ChannelID string    `db:"channel_id"`
+	MessageID string    `db:"message_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// RankSnapshot records a player's standing in one ranked queue at a point in
+// time. Snapshots taken right after a ranked match carry its ID and, when it
+// could be attributed to that match alone, the LP change.
+type RankSnapshot struct {
+	ID           int       `db:"id"`
+	PUUID        string    `db:"puuid"`
+	QueueType    string    `db:"queue_type"`
+	Tier         string    `db:"tier"`
+	Rank         string    `db:"rank"`
+	LeaguePoints int       `db:"league_points"`
+	Wins         int       `db:"wins"`
+	Losses       int       `db:"losses"`
+	MatchID      string    `db:"match_id"`
+	LPChange     *int      `db:"lp_change"`
+	CapturedAt   time.Time `db:"captured_at"`
+}
+
+// Backfill job statuses. A job stays pending, resuming after restarts, until
+// it has walked the requested history.
+const (
+	BackfillStatusPending = "pending"
+	BackfillStatusDone    = "done"
+	BackfillStatusFailed  = "failed"
+)
+
+// BackfillJob imports a tracked player's past matches. NextIndex is the
+// position in their match history (newest first) to resume from.
+type BackfillJob struct {
+	ID        int       `db:"id"`
+	PUUID     string    `db:"puuid"`
+	Since     time.Time `db:"since"` // zero means no time limit
+	MaxGames  int       `db:"max_games"`
+	NextIndex int       `db:"next_index"`
+	Stored    int       `db:"stored"`
+	Status    string    `db:"status"`
+	LastError string    `db:"last_error"`
+	// AppID, InteractionToken and MessageID identify the message that
+	// shows the job's progress; the token expires after 15 minutes.
+	AppID            string    `db:"app_id"`
+	InteractionToken string    `db:"interaction_token"`
+	MessageID        string    `db:"message_id"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+}
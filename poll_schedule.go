@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+)
+
+// Poll states, from most to least frequently checked. A player's state is
+// recomputed after every check from spectator-v5 and their last game time.
+const (
+	PollStateInGame  = "in_game"
+	PollStateActive  = "active"
+	PollStateRecent  = "recent"
+	PollStateIdle    = "idle"
+	PollStateDormant = "dormant"
+)
+
+const (
+	defaultPollInterval = 5 * time.Minute
+
+	// Thresholds on time since the player's last game.
+	activeWindow = 6 * time.Hour
+	recentWindow = 3 * 24 * time.Hour
+	idleWindow   = 14 * 24 * time.Hour
+
+	recentPollInterval  = 30 * time.Minute
+	idlePollInterval    = time.Hour
+	dormantPollInterval = 24 * time.Hour
+)
+
+// classifyPollState picks a player's poll state. lastGame is the start of
+// their most recent known game, or the time tracking began if none is known.
+func classifyPollState(inGame bool, lastGame, now time.Time) string {
+	since := now.Sub(lastGame)
+	switch {
+	case inGame:
+		return PollStateInGame
+	case since < activeWindow:
+		return PollStateActive
+	case since < recentWindow:
+		return PollStateRecent
+	case since < idleWindow:
+		return PollStateIdle
+	default:
+		return PollStateDormant
+	}
+}
+
+// pollInterval returns how long to wait before checking a player in state
+// again. The slower tiers never poll faster than base.
+func pollInterval(state string, base time.Duration) time.Duration {
+	var interval time.Duration
+	switch state {
+	case PollStateInGame, PollStateActive:
+		return base
+	case PollStateRecent:
+		interval = recentPollInterval
+	case PollStateIdle:
+		interval = idlePollInterval
+	default:
+		interval = dormantPollInterval
+	}
+	if interval < base {
+		return base
+	}
+	return interval
+}
+
+// pollStateLabel is the human readable form shown in /tracked.
+func pollStateLabel(state string) string {
+	switch state {
+	case PollStateInGame:
+		return "🎮 in game"
+	case PollStateActive:
+		return "🟢 active"
+	case PollStateRecent:
+		return "🟡 recent"
+	case PollStateIdle:
+		return "🟠 idle"
+	case PollStateDormant:
+		return "💤 dormant"
+	default:
+		return "⏳ pending first check"
+	}
+}
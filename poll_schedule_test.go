@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyPollState(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		inGame bool
+		since  time.Duration
+		want   string
+	}{
+		{inGame: true, since: 30 * 24 * time.Hour, want: PollStateInGame},
+		{since: 0, want: PollStateActive},
+		{since: activeWindow - time.Minute, want: PollStateActive},
+		{since: activeWindow, want: PollStateRecent},
+		{since: recentWindow - time.Minute, want: PollStateRecent},
+		{since: recentWindow, want: PollStateIdle},
+		{since: idleWindow - time.Minute, want: PollStateIdle},
+		{since: idleWindow, want: PollStateDormant},
+		{since: 365 * 24 * time.Hour, want: PollStateDormant},
+	}
+
+	for _, tt := range tests {
+		if got := classifyPollState(tt.inGame, now.Add(-tt.since), now); got != tt.want {
+			t.Errorf("classifyPollState(%v, now-%s) = %s, want %s", tt.inGame, tt.since, got, tt.want)
+		}
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	tests := []struct {
+		state string
+		base  time.Duration
+		want  time.Duration
+	}{
+		{state: PollStateInGame, base: defaultPollInterval, want: defaultPollInterval},
+		{state: PollStateActive, base: defaultPollInterval, want: defaultPollInterval},
+		{state: PollStateRecent, base: defaultPollInterval, want: recentPollInterval},
+		{state: PollStateIdle, base: defaultPollInterval, want: idlePollInterval},
+		{state: PollStateDormant, base: defaultPollInterval, want: dormantPollInterval},
+		{state: "", base: defaultPollInterval, want: dormantPollInterval},
+		// A slow base interval is never sped up
+		{state: PollStateRecent, base: 2 * time.Hour, want: 2 * time.Hour},
+		{state: PollStateIdle, base: 2 * time.Hour, want: 2 * time.Hour},
+		{state: PollStateDormant, base: 2 * time.Hour, want: dormantPollInterval},
+	}
+
+	for _, tt := range tests {
+		if got := pollInterval(tt.state, tt.base); got != tt.want {
+			t.Errorf("pollInterval(%q, %s) = %s, want %s", tt.state, tt.base, got, tt.want)
+		}
+	}
+}
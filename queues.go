@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// queueNames maps Riot queue IDs (gameQueueConfigId in spectator-v5, queueId
+// in match-v5) to display names.
+var queueNames = map[int]string{
+	400:  "Normal Draft",
+	420:  "Ranked Solo/Duo",
+	430:  "Normal Blind",
+	440:  "Ranked Flex",
+	450:  "ARAM",
+	480:  "Swiftplay",
+	490:  "Quickplay",
+	700:  "Clash",
+	720:  "ARAM Clash",
+	830:  "Co-op vs AI (Intro)",
+	840:  "Co-op vs AI (Beginner)",
+	850:  "Co-op vs AI (Intermediate)",
+	870:  "Co-op vs AI (Intro)",
+	880:  "Co-op vs AI (Beginner)",
+	890:  "Co-op vs AI (Intermediate)",
+	900:  "ARURF",
+	1020: "One for All",
+	1300: "Nexus Blitz",
+	1700: "Arena",
+	1710: "Arena",
+	1900: "URF",
+	2400: "ARAM: Mayhem",
+}
+
+func queueName(queueID int) string {
+	if name, ok := queueNames[queueID]; ok {
+		return name
+	}
+	return fmt.Sprintf("Queue %d", queueID)
+}
+
+// liveQueueName names the queue of a game in progress. Custom games have no
+// queue; match-v5 never lists them, so only live games need this.
+func liveQueueName(game *ActiveGame) string {
+	if game.GameType == "CUSTOM_GAME" {
+		return "Custom"
+	}
+	return queueName(game.GameQueueConfigID)
+}
+
+// matchQueueName names the queue a stored match was played in. Matches stored
+// before queue IDs were recorded fall back to their game mode.
+func matchQueueName(match *MatchData) string {
+	if match.QueueID != 0 {
+		return queueName(match.QueueID)
+	}
+	return gameModeName(match.GameMode)
+}
+
+// gameModeName renders a match-v5 gameMode such as "CLASSIC" or
+// "ONEFORALL" as "Classic".
+func gameModeName(gameMode string) string {
+	words := strings.Fields(strings.ReplaceAll(gameMode, "_", " "))
+	for idx, word := range words {
+		words[idx] = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+	}
+	return strings.Join(words, " ")
+}
+
+// queueFilter is a /stats queue option value and the queue IDs it covers.
+type queueFilter struct {
+	Value    string
+	Name     string
+	QueueIDs []int
+}
+
+var queueFilters = []queueFilter{
+	{Value: "solo", Name: "Ranked Solo/Duo", QueueIDs: []int{420}},
+	{Value: "flex", Name: "Ranked Flex", QueueIDs: []int{440}},
+	{Value: "ranked", Name: "Ranked (Solo/Duo and Flex)", QueueIDs: []int{420, 440}},
+	{Value: "normal", Name: "Normals", QueueIDs: []int{400, 430, 480, 490}},
+	{Value: "aram", Name: "ARAM", QueueIDs: []int{450, 720, 2400}},
+	{Value: "arena", Name: "Arena", QueueIDs: []int{1700, 1710}},
+}
+
+// findQueueFilter returns the filter for a queue option value, or nil for
+// "" (all queues) and unknown values.
+func findQueueFilter(value string) *queueFilter {
+	for idx := range queueFilters {
+		if queueFilters[idx].Value == value {
+			return &queueFilters[idx]
+		}
+	}
+	return nil
+}
+
+// queueFilterChoices builds the choice list for slash command queue options.
+func queueFilterChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(queueFilters))
+	for _, filter := range queueFilters {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  filter.Name,
+			Value: filter.Value,
+		})
+	}
+	return choices
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+func newRankSnapshot(puuid string, entry LeagueEntry) *RankSnapshot {
+	return &RankSnapshot{
+		PUUID:        puuid,
+		QueueType:    entry.QueueType,
+		Tier:         entry.Tier,
+		Rank:         entry.Rank,
+		LeaguePoints: entry.LeaguePoints,
+		Wins:         entry.Wins,
+		Losses:       entry.Losses,
+		CapturedAt:   time.Now(),
+	}
+}
+
+// sameStanding reports whether two snapshots record the same rank and record.
+func sameStanding(a, b *RankSnapshot) bool {
+	return a.Tier == b.Tier && a.Rank == b.Rank && a.LeaguePoints == b.LeaguePoints &&
+		a.Wins == b.Wins && a.Losses == b.Losses
+}
+
+// recordRankSnapshots stores puuid's ranked standings, skipping queues whose
+// standing has not changed since the last snapshot.
+func recordRankSnapshots(db Store, puuid string, entries []LeagueEntry) error {
+	for _, entry := range entries {
+		if entry.QueueType != RankedSoloQueue && entry.QueueType != RankedFlexQueue {
+			continue
+		}
+		snapshot := newRankSnapshot(puuid, entry)
+		latest, err := db.GetRankSnapshotBefore(puuid, entry.QueueType, snapshot.CapturedAt)
+		if err != nil {
+			return err
+		}
+		if latest != nil && sameStanding(latest, snapshot) {
+			continue
+		}
+		if err := db.AddRankSnapshot(snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotRanks refreshes player's rank snapshots, e.g. when they start a
+// ranked game, so the next match has an up to date baseline.
+func (gm *GameMonitor) snapshotRanks(ctx context.Context, player TrackedPlayer) error {
+	entries, err := gm.riotAPI.GetLeagueEntries(ctx, player.Platform, player.PUUID)
+	if err != nil {
+		return err
+	}
+	return recordRankSnapshots(gm.db, player.PUUID, entries)
+}
+
+// rankChange looks up player's standing after a ranked match and compares it
+// with the snapshot from before the game. It returns nil for unranked queues
+// and when the lookup fails, since a missing rank line should never hold up
+// the summary.
+func (gm *GameMonitor) rankChange(ctx context.Context, player TrackedPlayer, match *MatchData) *RankChange {
+	queueType, ok := rankedQueueTypes[match.QueueID]
+	if !ok {
+		return nil
+	}
+
+	entries, err := gm.riotAPI.GetLeagueEntries(ctx, player.Platform, player.PUUID)
+	if err != nil {
+		log.Printf("Error getting rank for %s#%s: %v", player.GameName, player.TagLine, err)
+		return nil
+	}
+	var after *RankSnapshot
+	for _, entry := range entries {
+		if entry.QueueType == queueType {
+			after = newRankSnapshot(player.PUUID, entry)
+			break
+		}
+	}
+	if after == nil {
+		// Still in placements.
+		return nil
+	}
+
+	gameEnd := match.GameCreation.Add(time.Duration(match.GameDuration) * time.Second)
+	before, err := gm.db.GetRankSnapshotBefore(player.PUUID, queueType, gameEnd)
+	if err != nil {
+		log.Printf("Error getting rank snapshot for %s#%s: %v", player.GameName, player.TagLine, err)
+		return nil
+	}
+
+	change := newRankChange(before, after)
+	if change.Attributed {
+		// Only tie the snapshot to the match when it is the only game between
+		// the two; otherwise it is just a fresh baseline.
+		after.MatchID = match.MatchID
+		after.LPChange = &change.LPChange
+		err = gm.db.AddRankSnapshot(after)
+	} else {
+		err = recordRankSnapshots(gm.db, player.PUUID, []LeagueEntry{*after.leagueEntry()})
+	}
+	if err != nil {
+		log.Printf("Error saving rank snapshot for %s#%s: %v", player.GameName, player.TagLine, err)
+	}
+	return change
+}
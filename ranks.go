@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// league-v4 queue types.
+const (
+	RankedSoloQueue = "RANKED_SOLO_5x5"
+	RankedFlexQueue = "RANKED_FLEX_SR"
+)
+
+// primaryLeagueEntry picks the entry that best represents a player's rank:
+// Solo/Duo if they have one, otherwise Flex.
+func primaryLeagueEntry(entries []LeagueEntry) *LeagueEntry {
+	var flex *LeagueEntry
+	for idx := range entries {
+		switch entries[idx].QueueType {
+		case RankedSoloQueue:
+			return &entries[idx]
+		case RankedFlexQueue:
+			flex = &entries[idx]
+		}
+	}
+	return flex
+}
+
+// isApexTier reports whether tier has no divisions.
+func isApexTier(tier string) bool {
+	return tier == "MASTER" || tier == "GRANDMASTER" || tier == "CHALLENGER"
+}
+
+// formatRank renders an entry as e.g. "Gold II 45 LP" or "Master 120 LP".
+func formatRank(entry *LeagueEntry) string {
+	if entry == nil || entry.Tier == "" {
+		return "Unranked"
+	}
+	return fmt.Sprintf("%s %d LP", rankName(entry.Tier, entry.Rank), entry.LeaguePoints)
+}
+
+// rankedQueueTypes maps the match-v5 queue IDs that award LP to their
+// league-v4 queue type.
+var rankedQueueTypes = map[int]string{
+	420: RankedSoloQueue,
+	440: RankedFlexQueue,
+}
+
+// tierOrder lists the tiers from lowest to highest. Apex tiers share one LP
+// ladder starting at Master.
+var tierOrder = []string{"IRON", "BRONZE", "SILVER", "GOLD", "PLATINUM", "EMERALD", "DIAMOND", "MASTER", "GRANDMASTER", "CHALLENGER"}
+
+var divisionOrder = map[string]int{"IV": 0, "III": 1, "II": 2, "I": 3}
+
+func tierIndex(tier string) int {
+	for idx, name := range tierOrder {
+		if name == tier {
+			return idx
+		}
+	}
+	return -1
+}
+
+// ladderPoints places a rank on a single LP scale (100 LP per division) so
+// that changes across promotions and demotions can be subtracted.
+func ladderPoints(tier, division string, lp int) int {
+	if isApexTier(tier) {
+		return tierIndex("MASTER")*400 + lp
+	}
+	return tierIndex(tier)*400 + divisionOrder[division]*100 + lp
+}
+
+// rankStep orders tiers and divisions (ignoring LP) for promotion callouts.
+func rankStep(tier, division string) int {
+	if isApexTier(tier) {
+		return tierIndex(tier) * 4
+	}
+	return tierIndex(tier)*4 + divisionOrder[division]
+}
+
+// leagueEntry converts a snapshot back for formatRank.
+func (s *RankSnapshot) leagueEntry() *LeagueEntry {
+	return &LeagueEntry{
+		QueueType:    s.QueueType,
+		Tier:         s.Tier,
+		Rank:         s.Rank,
+		LeaguePoints: s.LeaguePoints,
+		Wins:         s.Wins,
+		Losses:       s.Losses,
+	}
+}
+
+// RankChange describes a player's standing after a ranked match. LPChange is
+// only meaningful when Attributed is set, i.e. the before snapshot is exactly
+// one game behind After.
+type RankChange struct {
+	Before     *RankSnapshot
+	After      *RankSnapshot
+	LPChange   int
+	Attributed bool
+}
+
+// newRankChange compares a player's standing before and after a match.
+// before may be nil when there is no earlier snapshot.
+func newRankChange(before, after *RankSnapshot) *RankChange {
+	change := &RankChange{Before: before, After: after}
+	if before == nil {
+		return change
+	}
+	gamesPlayed := (after.Wins + after.Losses) - (before.Wins + before.Losses)
+	if gamesPlayed == 1 {
+		change.Attributed = true
+		change.LPChange = ladderPoints(after.Tier, after.Rank, after.LeaguePoints) -
+			ladderPoints(before.Tier, before.Rank, before.LeaguePoints)
+	}
+	return change
+}
+
+// String renders the change as e.g. "+18 LP (Gold II 45 LP)".
+func (c *RankChange) String() string {
+	current := formatRank(c.After.leagueEntry())
+	if !c.Attributed {
+		return current
+	}
+	return fmt.Sprintf("%+d LP (%s)", c.LPChange, current)
+}
+
+// Callout announces a promotion or demotion, or returns "" when the player
+// stayed in the same division.
+func (c *RankChange) Callout() string {
+	if c.Before == nil || !c.Attributed {
+		return ""
+	}
+	before := rankStep(c.Before.Tier, c.Before.Rank)
+	after := rankStep(c.After.Tier, c.After.Rank)
+	switch {
+	case after > before:
+		return fmt.Sprintf("⬆️ Promoted to %s!", rankName(c.After.Tier, c.After.Rank))
+	case after < before:
+		return fmt.Sprintf("⬇️ Demoted to %s", rankName(c.After.Tier, c.After.Rank))
+	default:
+		return ""
+	}
+}
+
+// rankName renders a tier and division without LP, e.g. "Gold II".
+func rankName(tier, division string) string {
+	name := strings.ToUpper(tier[:1]) + strings.ToLower(tier[1:])
+	if isApexTier(tier) {
+		return name
+	}
+	return name + " " + division
+}
@@ -0,0 +1,112 @@
+package main
+
+import "testing"
+
+func TestLadderPoints(t *testing.T) {
+	tests := []struct {
+		tier, division string
+		lp             int
+		want           int
+	}{
+		{tier: "IRON", division: "IV", lp: 0, want: 0},
+		{tier: "IRON", division: "I", lp: 99, want: 399},
+		{tier: "BRONZE", division: "IV", lp: 0, want: 400},
+		{tier: "GOLD", division: "II", lp: 45, want: 3*400 + 2*100 + 45},
+		{tier: "DIAMOND", division: "I", lp: 75, want: 6*400 + 3*100 + 75},
+		// Apex tiers share one ladder from Master 0 LP
+		{tier: "MASTER", division: "I", lp: 0, want: 7 * 400},
+		{tier: "GRANDMASTER", division: "I", lp: 350, want: 7*400 + 350},
+		{tier: "CHALLENGER", division: "I", lp: 1200, want: 7*400 + 1200},
+	}
+
+	for _, tt := range tests {
+		if got := ladderPoints(tt.tier, tt.division, tt.lp); got != tt.want {
+			t.Errorf("ladderPoints(%s, %s, %d) = %d, want %d", tt.tier, tt.division, tt.lp, got, tt.want)
+		}
+	}
+}
+
+func TestNewRankChange(t *testing.T) {
+	snapshot := func(tier, division string, lp, wins, losses int) *RankSnapshot {
+		return &RankSnapshot{QueueType: RankedSoloQueue, Tier: tier, Rank: division, LeaguePoints: lp,
+			Wins: wins, Losses: losses}
+	}
+
+	tests := []struct {
+		name           string
+		before, after  *RankSnapshot
+		wantAttributed bool
+		wantLPChange   int
+		wantString     string
+		wantCallout    string
+	}{
+		{
+			name:       "first snapshot",
+			after:      snapshot("GOLD", "II", 45, 10, 10),
+			wantString: "Gold II 45 LP",
+		},
+		{
+			name:           "win in the same division",
+			before:         snapshot("GOLD", "II", 45, 10, 10),
+			after:          snapshot("GOLD", "II", 63, 11, 10),
+			wantAttributed: true,
+			wantLPChange:   18,
+			wantString:     "+18 LP (Gold II 63 LP)",
+		},
+		{
+			name:           "promotion",
+			before:         snapshot("GOLD", "I", 90, 10, 10),
+			after:          snapshot("PLATINUM", "IV", 10, 11, 10),
+			wantAttributed: true,
+			wantLPChange:   20,
+			wantString:     "+20 LP (Platinum IV 10 LP)",
+			wantCallout:    "⬆️ Promoted to Platinum IV!",
+		},
+		{
+			name:           "demotion",
+			before:         snapshot("GOLD", "IV", 5, 10, 10),
+			after:          snapshot("SILVER", "I", 75, 10, 11),
+			wantAttributed: true,
+			wantLPChange:   -30,
+			wantString:     "-30 LP (Silver I 75 LP)",
+			wantCallout:    "⬇️ Demoted to Silver I",
+		},
+		{
+			name:           "apex tiers",
+			before:         snapshot("MASTER", "I", 480, 10, 10),
+			after:          snapshot("GRANDMASTER", "I", 500, 11, 10),
+			wantAttributed: true,
+			wantLPChange:   20,
+			wantString:     "+20 LP (Grandmaster 500 LP)",
+			wantCallout:    "⬆️ Promoted to Grandmaster!",
+		},
+		{
+			name:       "several games since the last snapshot",
+			before:     snapshot("GOLD", "II", 45, 10, 10),
+			after:      snapshot("GOLD", "I", 20, 13, 10),
+			wantString: "Gold I 20 LP",
+		},
+		{
+			name:       "no new games",
+			before:     snapshot("GOLD", "II", 45, 10, 10),
+			after:      snapshot("GOLD", "II", 45, 10, 10),
+			wantString: "Gold II 45 LP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := newRankChange(tt.before, tt.after)
+			if change.Attributed != tt.wantAttributed || change.LPChange != tt.wantLPChange {
+				t.Errorf("attributed %v with %d LP, want %v with %d LP", change.Attributed, change.LPChange,
+					tt.wantAttributed, tt.wantLPChange)
+			}
+			if got := change.String(); got != tt.wantString {
+				t.Errorf("String() = %q, want %q", got, tt.wantString)
+			}
+			if got := change.Callout(); got != tt.wantCallout {
+				t.Errorf("Callout() = %q, want %q", got, tt.wantCallout)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Riot development key limits, used until the first response tells us the
+// real application limits.
+var defaultAppRateLimits = []rateLimit{
+	{count: 20, window: time.Second},
+	{count: 100, window: 2 * time.Minute},
+}
+
+type rateLimit struct {
+	count  int
+	window time.Duration
+}
+
+// rateBucket hands out up to limit.count tokens per window. The window starts
+// with the first request, which matches how Riot counts its own limits.
+type rateBucket struct {
+	limit   rateLimit
+	used    int
+	resetAt time.Time
+}
+
+func (b *rateBucket) wait(now time.Time) time.Duration {
+	if now.After(b.resetAt) {
+		return 0
+	}
+	if b.used < b.limit.count {
+		return 0
+	}
+	return b.resetAt.Sub(now)
+}
+
+func (b *rateBucket) take(now time.Time) {
+	if now.After(b.resetAt) {
+		b.used = 0
+		b.resetAt = now.Add(b.limit.window)
+	}
+	b.used++
+}
+
+// RateLimiter paces Riot API calls against the application and per-method
+// buckets of each routing host. Callers block in Wait until every bucket has a
+// token, so a burst of monitor work queues up instead of failing with 429s.
+type RateLimiter struct {
+	mu           sync.Mutex
+	app          map[string][]*rateBucket
+	methods      map[string][]*rateBucket
+	blockedUntil map[string]time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		app:          make(map[string][]*rateBucket),
+		methods:      make(map[string][]*rateBucket),
+		blockedUntil: make(map[string]time.Time),
+	}
+}
+
+// Reset forgets all learned limits and counts, e.g. after the API key changes.
+func (rl *RateLimiter) Reset() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.app = make(map[string][]*rateBucket)
+	rl.methods = make(map[string][]*rateBucket)
+	rl.blockedUntil = make(map[string]time.Time)
+}
+
+// Wait blocks until a request to method on host is allowed and reserves a
+// token for it. It returns how long the caller was held back, or ctx's error
+// if it is cancelled while queued.
+func (rl *RateLimiter) Wait(ctx context.Context, host, method string) (time.Duration, error) {
+	start := time.Now()
+	for {
+		rl.mu.Lock()
+		now := time.Now()
+		delay := rl.delayLocked(host, method, now)
+		if delay <= 0 {
+			for _, b := range rl.appBucketsLocked(host) {
+				b.take(now)
+			}
+			for _, b := range rl.methods[methodKey(host, method)] {
+				b.take(now)
+			}
+			rl.mu.Unlock()
+			return time.Since(start), nil
+		}
+		rl.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Since(start), ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (rl *RateLimiter) delayLocked(host, method string, now time.Time) time.Duration {
+	var delay time.Duration
+	for _, key := range []string{host, methodKey(host, method)} {
+		if until, ok := rl.blockedUntil[key]; ok {
+			if d := until.Sub(now); d > delay {
+				delay = d
+			}
+		}
+	}
+	for _, b := range rl.appBucketsLocked(host) {
+		if d := b.wait(now); d > delay {
+			delay = d
+		}
+	}
+	for _, b := range rl.methods[methodKey(host, method)] {
+		if d := b.wait(now); d > delay {
+			delay = d
+		}
+	}
+	return delay
+}
+
+func (rl *RateLimiter) appBucketsLocked(host string) []*rateBucket {
+	buckets, ok := rl.app[host]
+	if !ok {
+		buckets = newRateBuckets(defaultAppRateLimits)
+		rl.app[host] = buckets
+	}
+	return buckets
+}
+
+// Update adjusts the buckets for host and method from the rate limit headers
+// of a response. A 429 blocks the offending scope for its Retry-After period
+// and returns that period.
+func (rl *RateLimiter) Update(host, method string, resp *http.Response) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if limits := parseRateLimits(resp.Header.Get("X-App-Rate-Limit")); len(limits) > 0 {
+		rl.app[host] = syncRateBuckets(rl.app[host], limits,
+			parseRateLimits(resp.Header.Get("X-App-Rate-Limit-Count")), now)
+	}
+	key := methodKey(host, method)
+	if limits := parseRateLimits(resp.Header.Get("X-Method-Rate-Limit")); len(limits) > 0 {
+		rl.methods[key] = syncRateBuckets(rl.methods[key], limits,
+			parseRateLimits(resp.Header.Get("X-Method-Rate-Limit-Count")), now)
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+	// Method and service limits only affect this endpoint; everything else
+	// (including a missing header) is treated as the application limit.
+	scope := host
+	if resp.Header.Get("X-Rate-Limit-Type") == "method" || resp.Header.Get("X-Rate-Limit-Type") == "service" {
+		scope = key
+	}
+	if until := now.Add(retryAfter); until.After(rl.blockedUntil[scope]) {
+		rl.blockedUntil[scope] = until
+	}
+	return retryAfter
+}
+
+func methodKey(host, method string) string {
+	return host + "|" + method
+}
+
+func newRateBuckets(limits []rateLimit) []*rateBucket {
+	buckets := make([]*rateBucket, 0, len(limits))
+	for _, limit := range limits {
+		buckets = append(buckets, &rateBucket{limit: limit})
+	}
+	return buckets
+}
+
+// syncRateBuckets replaces buckets with the advertised limits, keeping local
+// window state where it exists and trusting Riot's count when it is higher
+// than ours (e.g. another process shares the key).
+func syncRateBuckets(existing []*rateBucket, limits, counts []rateLimit, now time.Time) []*rateBucket {
+	byWindow := make(map[time.Duration]*rateBucket, len(existing))
+	for _, b := range existing {
+		byWindow[b.limit.window] = b
+	}
+
+	buckets := make([]*rateBucket, 0, len(limits))
+	for _, limit := range limits {
+		b, ok := byWindow[limit.window]
+		if !ok {
+			b = &rateBucket{}
+		}
+		b.limit = limit
+		for _, c := range counts {
+			if c.window != limit.window || c.count <= b.used {
+				continue
+			}
+			if now.After(b.resetAt) {
+				b.resetAt = now.Add(limit.window)
+			}
+			b.used = c.count
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+// parseRateLimits parses Riot's "count:seconds,count:seconds" header format.
+func parseRateLimits(header string) []rateLimit {
+	if header == "" {
+		return nil
+	}
+
+	var limits []rateLimit
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.SplitN(strings.TrimSpace(part), ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		count, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		seconds, err := strconv.Atoi(fields[1])
+		if err != nil || seconds <= 0 {
+			continue
+		}
+		limits = append(limits, rateLimit{count: count, window: time.Duration(seconds) * time.Second})
+	}
+	return limits
+}
+
+func parseRetryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	// Riot omits Retry-After when the underlying service is throttling us;
+	// back off for a short fixed period instead of hammering it.
+	return 5 * time.Second
+}
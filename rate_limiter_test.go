@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []rateLimit
+	}{
+		{header: "", want: nil},
+		{header: "20:1", want: []rateLimit{{count: 20, window: time.Second}}},
+		{
+			header: "20:1,100:120",
+			want:   []rateLimit{{count: 20, window: time.Second}, {count: 100, window: 2 * time.Minute}},
+		},
+		{header: " 500:10 , 30000:600 ", want: []rateLimit{{count: 500, window: 10 * time.Second}, {count: 30000, window: 10 * time.Minute}}},
+		// Malformed parts are skipped
+		{header: "20,abc:1,5:0,5:-1,7:x,3:2", want: []rateLimit{{count: 3, window: 2 * time.Second}}},
+	}
+
+	for _, tt := range tests {
+		if got := parseRateLimits(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRateLimits(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "10", want: 10 * time.Second},
+		{header: " 3 ", want: 3 * time.Second},
+		{header: "", want: 5 * time.Second},
+		{header: "0", want: 5 * time.Second},
+		{header: "soon", want: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestSyncRateBuckets(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	second := rateLimit{count: 20, window: time.Second}
+	twoMinutes := rateLimit{count: 100, window: 2 * time.Minute}
+
+	tests := []struct {
+		name     string
+		existing []*rateBucket
+		limits   []rateLimit
+		counts   []rateLimit
+		want     []rateBucket
+	}{
+		{
+			name:   "new buckets take Riot's counts",
+			limits: []rateLimit{second, twoMinutes},
+			counts: []rateLimit{{count: 3, window: time.Second}, {count: 40, window: 2 * time.Minute}},
+			want: []rateBucket{
+				{limit: second, used: 3, resetAt: now.Add(time.Second)},
+				{limit: twoMinutes, used: 40, resetAt: now.Add(2 * time.Minute)},
+			},
+		},
+		{
+			name:     "local count is kept when higher",
+			existing: []*rateBucket{{limit: second, used: 10, resetAt: now.Add(500 * time.Millisecond)}},
+			limits:   []rateLimit{second},
+			counts:   []rateLimit{{count: 4, window: time.Second}},
+			want:     []rateBucket{{limit: second, used: 10, resetAt: now.Add(500 * time.Millisecond)}},
+		},
+		{
+			name:     "higher remote count keeps the running window",
+			existing: []*rateBucket{{limit: second, used: 2, resetAt: now.Add(500 * time.Millisecond)}},
+			limits:   []rateLimit{second},
+			counts:   []rateLimit{{count: 15, window: time.Second}},
+			want:     []rateBucket{{limit: second, used: 15, resetAt: now.Add(500 * time.Millisecond)}},
+		},
+		{
+			name:     "changed limit replaces the bucket's limit",
+			existing: []*rateBucket{{limit: second, used: 5, resetAt: now.Add(time.Second)}},
+			limits:   []rateLimit{{count: 500, window: time.Second}},
+			want:     []rateBucket{{limit: rateLimit{count: 500, window: time.Second}, used: 5, resetAt: now.Add(time.Second)}},
+		},
+		{
+			name:     "windows no longer advertised are dropped",
+			existing: []*rateBucket{{limit: second, used: 5}, {limit: twoMinutes, used: 50}},
+			limits:   []rateLimit{twoMinutes},
+			want:     []rateBucket{{limit: twoMinutes, used: 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buckets := syncRateBuckets(tt.existing, tt.limits, tt.counts, now)
+			got := make([]rateBucket, 0, len(buckets))
+			for _, b := range buckets {
+				got = append(got, *b)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateBucket(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := &rateBucket{limit: rateLimit{count: 2, window: time.Second}}
+
+	for idx := 0; idx < 2; idx++ {
+		if wait := b.wait(now); wait != 0 {
+			t.Fatalf("request %d waits %s, want none", idx+1, wait)
+		}
+		b.take(now)
+	}
+	if wait := b.wait(now.Add(400 * time.Millisecond)); wait != 600*time.Millisecond {
+		t.Errorf("full bucket waits %s, want 600ms", wait)
+	}
+	if wait := b.wait(now.Add(1001 * time.Millisecond)); wait != 0 {
+		t.Errorf("bucket after its window waits %s, want none", wait)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultPlatform is used for players tracked before regions were supported
+// and when /track is called without a region.
+const DefaultPlatform = "na1"
+
+// platformRegions maps each League platform routing value to the regional
+// cluster that serves match-v5 for it.
+var platformRegions = map[string]string{
+	"na1":  "americas",
+	"br1":  "americas",
+	"la1":  "americas",
+	"la2":  "americas",
+	"euw1": "europe",
+	"eun1": "europe",
+	"tr1":  "europe",
+	"ru":   "europe",
+	"me1":  "europe",
+	"kr":   "asia",
+	"jp1":  "asia",
+	"oc1":  "sea",
+	"ph2":  "sea",
+	"sg2":  "sea",
+	"th2":  "sea",
+	"tw2":  "sea",
+	"vn2":  "sea",
+}
+
+// platformNames are the labels shown for the /track region option.
+var platformNames = map[string]string{
+	"na1":  "North America",
+	"br1":  "Brazil",
+	"la1":  "Latin America North",
+	"la2":  "Latin America South",
+	"euw1": "Europe West",
+	"eun1": "Europe Nordic & East",
+	"tr1":  "Turkey",
+	"ru":   "Russia",
+	"me1":  "Middle East",
+	"kr":   "Korea",
+	"jp1":  "Japan",
+	"oc1":  "Oceania",
+	"ph2":  "Philippines",
+	"sg2":  "Singapore",
+	"th2":  "Thailand",
+	"tw2":  "Taiwan",
+	"vn2":  "Vietnam",
+}
+
+// normalizePlatform lower-cases and validates a platform routing value. An
+// empty value falls back to DefaultPlatform.
+func normalizePlatform(platform string) (string, error) {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if platform == "" {
+		return DefaultPlatform, nil
+	}
+	if _, ok := platformRegions[platform]; !ok {
+		return "", fmt.Errorf("unknown platform %q", platform)
+	}
+	return platform, nil
+}
+
+func platformRegion(platform string) string {
+	if region, ok := platformRegions[platform]; ok {
+		return region
+	}
+	return platformRegions[DefaultPlatform]
+}
+
+// platformHost serves platform-scoped endpoints such as summoner-v4.
+func platformHost(platform string) string {
+	if _, ok := platformRegions[platform]; !ok {
+		platform = DefaultPlatform
+	}
+	return platform + ".api.riotgames.com"
+}
+
+// matchHost serves match-v5 for the platform's regional cluster.
+func matchHost(platform string) string {
+	return platformRegion(platform) + ".api.riotgames.com"
+}
+
+// accountHost serves account-v1. Accounts are global, but the sea cluster does
+// not host account-v1, so those platforms are routed through asia.
+func accountHost(platform string) string {
+	region := platformRegion(platform)
+	if region == "sea" {
+		region = "asia"
+	}
+	return region + ".api.riotgames.com"
+}
+
+// platformChoices builds the choice list for slash command region options.
+func platformChoices() []*discordgo.ApplicationCommandOptionChoice {
+	platforms := make([]string, 0, len(platformRegions))
+	for platform := range platformRegions {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(platforms))
+	for _, platform := range platforms {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s (%s)", platformNames[platform], strings.ToUpper(platform)),
+			Value: platform,
+		})
+	}
+	return choices
+}
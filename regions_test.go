@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestPlatformRouting(t *testing.T) {
+	tests := []struct {
+		platform string
+		match    string
+		account  string
+	}{
+		{platform: "na1", match: "americas", account: "americas"},
+		{platform: "br1", match: "americas", account: "americas"},
+		{platform: "la1", match: "americas", account: "americas"},
+		{platform: "la2", match: "americas", account: "americas"},
+		{platform: "euw1", match: "europe", account: "europe"},
+		{platform: "eun1", match: "europe", account: "europe"},
+		{platform: "tr1", match: "europe", account: "europe"},
+		{platform: "ru", match: "europe", account: "europe"},
+		{platform: "me1", match: "europe", account: "europe"},
+		{platform: "kr", match: "asia", account: "asia"},
+		{platform: "jp1", match: "asia", account: "asia"},
+		// sea serves match-v5 but not account-v1
+		{platform: "oc1", match: "sea", account: "asia"},
+		{platform: "ph2", match: "sea", account: "asia"},
+		{platform: "sg2", match: "sea", account: "asia"},
+		{platform: "th2", match: "sea", account: "asia"},
+		{platform: "tw2", match: "sea", account: "asia"},
+		{platform: "vn2", match: "sea", account: "asia"},
+		// Unknown platforms fall back to DefaultPlatform
+		{platform: "xx1", match: "americas", account: "americas"},
+	}
+
+	if len(tests)-1 != len(platformRegions) {
+		t.Errorf("testing %d platforms, but %d are known", len(tests)-1, len(platformRegions))
+	}
+	for _, tt := range tests {
+		if got, want := matchHost(tt.platform), tt.match+".api.riotgames.com"; got != want {
+			t.Errorf("matchHost(%q) = %s, want %s", tt.platform, got, want)
+		}
+		if got, want := accountHost(tt.platform), tt.account+".api.riotgames.com"; got != want {
+			t.Errorf("accountHost(%q) = %s, want %s", tt.platform, got, want)
+		}
+	}
+}
+
+func TestPlatformHost(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{platform: "euw1", want: "euw1.api.riotgames.com"},
+		{platform: "kr", want: "kr.api.riotgames.com"},
+		{platform: "xx1", want: DefaultPlatform + ".api.riotgames.com"},
+		{platform: "", want: DefaultPlatform + ".api.riotgames.com"},
+	}
+
+	for _, tt := range tests {
+		if got := platformHost(tt.platform); got != tt.want {
+			t.Errorf("platformHost(%q) = %s, want %s", tt.platform, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "", want: DefaultPlatform},
+		{input: "euw1", want: "euw1"},
+		{input: " EUW1 ", want: "euw1"},
+		{input: "KR", want: "kr"},
+		{input: "euw", wantErr: true},
+		{input: "europe", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizePlatform(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizePlatform(%q) = %q, %v; want %q (error: %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+	for platform := range platformRegions {
+		if platformNames[platform] == "" {
+			t.Errorf("platform %s has no name for the region option", platform)
+		}
+	}
+}
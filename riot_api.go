@@ -1,19 +1,50 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
-	"github.com/bwmarrin/discordgo"
 )
 
 type RiotAPI struct {
-	APIKey string
-	Client *http.Client
-	DiscordSession *discordgo.Session
-	ChannelID string
+	Client  *http.Client
+	Limiter *RateLimiter
+	Hooks   RiotAPIHooks
+
+	keyMu        sync.RWMutex
+	apiKey       string
+	keyExpiresAt time.Time
+
+	champions championCache
+}
+
+// RiotAPIHooks are notified when a request finally fails with one of the
+// failure classes that need a human's attention. Any of them may be nil.
+type RiotAPIHooks struct {
+	OnAuthFailure        func(ctx context.Context, err *APIError)
+	OnRateLimited        func(ctx context.Context, err *APIError)
+	OnServiceUnavailable func(ctx context.Context, err *APIError)
+}
+
+type requesterKey struct{}
+
+// withRequester tags ctx with the Discord user a request is made for, so
+// error hooks can mention them. Background requests carry no requester.
+func withRequester(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, requesterKey{}, userID)
+}
+
+func requesterFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(requesterKey{}).(string)
+	return userID
 }
 
 type Account struct {
@@ -32,102 +63,217 @@ type Summoner struct {
 	SummonerLevel int    `json:"summonerLevel"`
 }
 
+// Match is a finished game from match-v5. Only the fields the bot reads are
+// decoded; Raw keeps the whole payload (teams, objectives, bans...) for
+// storage.
 type Match struct {
+	Metadata struct {
+		MatchID string `json:"matchId"`
+	} `json:"metadata"`
 	Info struct {
-		GameID       int64  `json:"gameId"`
-		GameMode     string `json:"gameMode"`
-		GameDuration int    `json:"gameDuration"`
-		GameCreation int64  `json:"gameCreation"`
-		Participants []struct {
-			PUUID              string `json:"puuid"`
-			ChampionName       string `json:"championName"`
-			Win                bool   `json:"win"`
-			Kills              int    `json:"kills"`
-			Deaths             int    `json:"deaths"`
-			Assists            int    `json:"assists"`
-			TotalMinionsKilled int    `json:"totalMinionsKilled"`
-			TotalDamageDealt   int    `json:"totalDamageDealtToChampions"`
-			TotalDamageTaken   int    `json:"totalDamageTaken"`
-			VisionScore        int    `json:"visionScore"`
-			GoldEarned         int    `json:"goldEarned"`
-			Item0              int    `json:"item0"`
-			Item1              int    `json:"item1"`
-			Item2              int    `json:"item2"`
-			Item3              int    `json:"item3"`
-			Item4              int    `json:"item4"`
-			Item5              int    `json:"item5"`
-			Item6              int    `json:"item6"`
-		} `json:"participants"`
+		GameID       int64              `json:"gameId"`
+		PlatformID   string             `json:"platformId"`
+		GameMode     string             `json:"gameMode"`
+		QueueID      int                `json:"queueId"`
+		GameDuration int                `json:"gameDuration"`
+		GameCreation int64              `json:"gameCreation"`
+		Participants []MatchParticipant `json:"participants"`
 	} `json:"info"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+// MatchParticipant is one player's line in a match.
+type MatchParticipant struct {
+	PUUID              string `json:"puuid"`
+	ParticipantID      int    `json:"participantId"`
+	TeamID             int    `json:"teamId"`
+	PlayerSubteamID    int    `json:"playerSubteamId"` // Arena pair, 0 elsewhere
+	TeamPosition       string `json:"teamPosition"`
+	RiotIDGameName     string `json:"riotIdGameName"`
+	RiotIDTagline      string `json:"riotIdTagline"`
+	ChampionID         int    `json:"championId"`
+	ChampionName       string `json:"championName"`
+	Win                bool   `json:"win"`
+	Kills              int    `json:"kills"`
+	Deaths             int    `json:"deaths"`
+	Assists            int    `json:"assists"`
+	TotalMinionsKilled int    `json:"totalMinionsKilled"`
+	TotalDamageDealt   int    `json:"totalDamageDealtToChampions"`
+	TotalDamageTaken   int    `json:"totalDamageTaken"`
+	VisionScore        int    `json:"visionScore"`
+	GoldEarned         int    `json:"goldEarned"`
+	Item0              int    `json:"item0"`
+	Item1              int    `json:"item1"`
+	Item2              int    `json:"item2"`
+	Item3              int    `json:"item3"`
+	Item4              int    `json:"item4"`
+	Item5              int    `json:"item5"`
+	Item6              int    `json:"item6"`
 }
 
-func NewRiotAPI(apiKey string, discordSession *discordgo.Session, channelID string) *RiotAPI {
+// ActiveGame is a game in progress from spectator-v5.
+type ActiveGame struct {
+	GameID            int64  `json:"gameId"`
+	GameMode          string `json:"gameMode"`
+	GameType          string `json:"gameType"` // CUSTOM_GAME for customs
+	GameQueueConfigID int    `json:"gameQueueConfigId"`
+	GameStartTime     int64  `json:"gameStartTime"`
+	PlatformID        string `json:"platformId"`
+	Participants      []struct {
+		PUUID      string `json:"puuid"`
+		RiotID     string `json:"riotId"`
+		ChampionID int    `json:"championId"`
+		TeamID     int    `json:"teamId"`
+		Bot        bool   `json:"bot"`
+	} `json:"participants"`
+}
+
+// LeagueEntry is a player's standing in one ranked queue from league-v4.
+type LeagueEntry struct {
+	QueueType    string `json:"queueType"`
+	Tier         string `json:"tier"`
+	Rank         string `json:"rank"`
+	LeaguePoints int    `json:"leaguePoints"`
+	Wins         int    `json:"wins"`
+	Losses       int    `json:"losses"`
+}
+
+func NewRiotAPI(apiKey string) *RiotAPI {
 	return &RiotAPI{
-		APIKey: apiKey,
+		apiKey: apiKey,
 		Client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		DiscordSession: discordSession,
-		ChannelID: channelID,
+		Limiter: NewRateLimiter(),
 	}
 }
 
-func (r *RiotAPI) makeRequest(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
+// APIKey returns the key currently used for requests.
+func (r *RiotAPI) APIKey() string {
+	r.keyMu.RLock()
+	defer r.keyMu.RUnlock()
+	return r.apiKey
+}
+
+// APIKeyExpiresAt returns when the current key expires, or the zero time if
+// that is unknown (e.g. a production key).
+func (r *RiotAPI) APIKeyExpiresAt() time.Time {
+	r.keyMu.RLock()
+	defer r.keyMu.RUnlock()
+	return r.keyExpiresAt
+}
+
+// SetAPIKey atomically replaces the key used for all subsequent requests.
+// Rate limits are per key, so the limiter starts over with fresh buckets.
+func (r *RiotAPI) SetAPIKey(apiKey string, expiresAt time.Time) {
+	r.keyMu.Lock()
+	r.apiKey = apiKey
+	r.keyExpiresAt = expiresAt
+	r.keyMu.Unlock()
 
-	req.Header.Set("X-Riot-Token", r.APIKey)
-	req.Header.Set("Accept", "application/json")
+	r.Limiter.Reset()
+}
 
-	resp, err := r.Client.Do(req)
+// ValidateKey checks apiKey against the cheap lol-status endpoint without
+// touching the key in use or firing error hooks.
+func (r *RiotAPI) ValidateKey(ctx context.Context, apiKey string) error {
+	endpoint := "status-platform-data"
+	resp, err := r.doRequest(ctx, endpoint, fmt.Sprintf("https://%s/lol/status/v4/platform-data", platformHost(DefaultPlatform)), apiKey)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return newAPIError(endpoint, resp)
 	}
-
-	return io.ReadAll(resp.Body)
+	return nil
 }
 
-func (r *RiotAPI) makeRequestWithUser(url string, userID string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// maxRateLimitRetries is how many times a request that comes back 429 is
+// retried after waiting out its Retry-After.
+const maxRateLimitRetries = 3
+
+// doRequest sends a GET through the rate limiter and retries 429 responses.
+// The caller must close the returned response body.
+func (r *RiotAPI) doRequest(ctx context.Context, endpoint, rawURL, apiKey string) (*http.Response, error) {
+	parsed, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-Riot-Token", r.APIKey)
-	req.Header.Set("Accept", "application/json")
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("X-Riot-Token", apiKey)
+		req.Header.Set("Accept", "application/json")
+
+		waited, err := r.Limiter.Wait(ctx, parsed.Host, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if waited > 0 {
+			riotRateLimitWait.WithLabelValues(endpoint).Observe(waited.Seconds())
+		}
+
+		start := time.Now()
+		resp, err := r.Client.Do(req)
+		if err != nil {
+			observeRiotRequest(endpoint, 0, start)
+			return nil, err
+		}
+		observeRiotRequest(endpoint, resp.StatusCode, start)
 
-	resp, err := r.Client.Do(req)
+		retryAfter := r.Limiter.Update(parsed.Host, endpoint, resp)
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= maxRateLimitRetries {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		log.Printf("Rate limited on %s, retrying in %s (attempt %d/%d)", endpoint, retryAfter, attempt+1, maxRateLimitRetries)
+	}
+}
+
+func (r *RiotAPI) makeRequest(ctx context.Context, endpoint, url string) ([]byte, error) {
+	resp, err := r.doRequest(ctx, endpoint, url, r.APIKey())
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 401 {
-		if r.DiscordSession != nil && r.ChannelID != "" {
-			message := fmt.Sprintf("我真是服了，<@weilei_>还不rotate key吗, <@%s>啥都用不了", userID)
-			r.DiscordSession.ChannelMessageSend(r.ChannelID, message)
-		}
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		apiErr := newAPIError(endpoint, resp)
+		r.notifyHooks(ctx, apiErr)
+		return nil, apiErr
 	}
 
 	return io.ReadAll(resp.Body)
 }
 
-func (r *RiotAPI) GetAccountByRiotID(gameName, tagLine string) (*Account, error) {
-	url := fmt.Sprintf("https://americas.api.riotgames.com/riot/account/v1/accounts/by-riot-id/%s/%s", gameName, tagLine)
+func (r *RiotAPI) notifyHooks(ctx context.Context, apiErr *APIError) {
+	var hook func(context.Context, *APIError)
+	switch apiErr.kind {
+	case ErrUnauthorized:
+		hook = r.Hooks.OnAuthFailure
+	case ErrRateLimited:
+		hook = r.Hooks.OnRateLimited
+	case ErrServiceUnavailable:
+		hook = r.Hooks.OnServiceUnavailable
+	}
+	if hook != nil {
+		hook(ctx, apiErr)
+	}
+}
+
+func (r *RiotAPI) GetAccountByRiotID(ctx context.Context, platform, gameName, tagLine string) (*Account, error) {
+	reqURL := fmt.Sprintf("https://%s/riot/account/v1/accounts/by-riot-id/%s/%s",
+		accountHost(platform), url.PathEscape(gameName), url.PathEscape(tagLine))
 
-	body, err := r.makeRequest(url)
+	body, err := r.makeRequest(ctx, "account-by-riot-id", reqURL)
 	if err != nil {
 		return nil, err
 	}
@@ -140,10 +286,11 @@ func (r *RiotAPI) GetAccountByRiotID(gameName, tagLine string) (*Account, error)
 	return &account, nil
 }
 
-func (r *RiotAPI) GetAccountByRiotIDWithUser(gameName, tagLine, userID string) (*Account, error) {
-	url := fmt.Sprintf("https://americas.api.riotgames.com/riot/account/v1/accounts/by-riot-id/%s/%s", gameName, tagLine)
+// GetAccountByPUUID returns puuid's current Riot ID.
+func (r *RiotAPI) GetAccountByPUUID(ctx context.Context, platform, puuid string) (*Account, error) {
+	reqURL := fmt.Sprintf("https://%s/riot/account/v1/accounts/by-puuid/%s", accountHost(platform), puuid)
 
-	body, err := r.makeRequestWithUser(url, userID)
+	body, err := r.makeRequest(ctx, "account-by-puuid", reqURL)
 	if err != nil {
 		return nil, err
 	}
@@ -156,10 +303,10 @@ func (r *RiotAPI) GetAccountByRiotIDWithUser(gameName, tagLine, userID string) (
 	return &account, nil
 }
 
-func (r *RiotAPI) GetSummonerByPUUID(puuid string) (*Summoner, error) {
-	url := fmt.Sprintf("https://na1.api.riotgames.com/lol/summoner/v4/summoners/by-puuid/%s", puuid)
+func (r *RiotAPI) GetSummonerByPUUID(ctx context.Context, platform, puuid string) (*Summoner, error) {
+	url := fmt.Sprintf("https://%s/lol/summoner/v4/summoners/by-puuid/%s", platformHost(platform), puuid)
 
-	body, err := r.makeRequest(url)
+	body, err := r.makeRequest(ctx, "summoner-by-puuid", url)
 	if err != nil {
 		return nil, err
 	}
@@ -172,82 +319,174 @@ func (r *RiotAPI) GetSummonerByPUUID(puuid string) (*Summoner, error) {
 	return &summoner, nil
 }
 
-func (r *RiotAPI) GetSummonerByPUUIDWithUser(puuid, userID string) (*Summoner, error) {
-	url := fmt.Sprintf("https://na1.api.riotgames.com/lol/summoner/v4/summoners/by-puuid/%s", puuid)
+// MatchHistoryQuery selects a page of a player's match-v5 match IDs, newest
+// first. A zero StartTime means no lower bound.
+type MatchHistoryQuery struct {
+	Start     int
+	Count     int
+	StartTime time.Time
+}
+
+func (r *RiotAPI) GetMatchHistory(ctx context.Context, platform, puuid string, count int) ([]string, error) {
+	return r.GetMatchIDs(ctx, platform, puuid, MatchHistoryQuery{Count: count})
+}
+
+func (r *RiotAPI) GetMatchIDs(ctx context.Context, platform, puuid string, query MatchHistoryQuery) ([]string, error) {
+	params := url.Values{}
+	params.Set("start", strconv.Itoa(query.Start))
+	params.Set("count", strconv.Itoa(query.Count))
+	if !query.StartTime.IsZero() {
+		params.Set("startTime", strconv.FormatInt(query.StartTime.Unix(), 10))
+	}
+	reqURL := fmt.Sprintf("https://%s/lol/match/v5/matches/by-puuid/%s/ids?%s", matchHost(platform), puuid, params.Encode())
 
-	body, err := r.makeRequestWithUser(url, userID)
+	body, err := r.makeRequest(ctx, "match-ids-by-puuid", reqURL)
 	if err != nil {
 		return nil, err
 	}
 
-	var summoner Summoner
-	if err := json.Unmarshal(body, &summoner); err != nil {
+	var matchIDs []string
+	if err := json.Unmarshal(body, &matchIDs); err != nil {
 		return nil, err
 	}
 
-	return &summoner, nil
+	return matchIDs, nil
 }
 
-func (r *RiotAPI) GetMatchHistory(puuid string, count int) ([]string, error) {
-	url := fmt.Sprintf("https://americas.api.riotgames.com/lol/match/v5/matches/by-puuid/%s/ids?count=%d", puuid, count)
+func (r *RiotAPI) GetMatchDetails(ctx context.Context, platform, matchID string) (*Match, error) {
+	url := fmt.Sprintf("https://%s/lol/match/v5/matches/%s", matchHost(platform), matchID)
 
-	body, err := r.makeRequest(url)
+	body, err := r.makeRequest(ctx, "match-by-id", url)
 	if err != nil {
 		return nil, err
 	}
 
-	var matchIDs []string
-	if err := json.Unmarshal(body, &matchIDs); err != nil {
+	return parseMatch(body)
+}
+
+// parseMatch decodes a match-v5 payload, keeping it in Raw.
+func parseMatch(payload []byte) (*Match, error) {
+	var match Match
+	if err := json.Unmarshal(payload, &match); err != nil {
 		return nil, err
 	}
+	match.Raw = payload
 
-	return matchIDs, nil
+	return &match, nil
 }
 
-func (r *RiotAPI) GetMatchDetails(matchID string) (*Match, error) {
-	url := fmt.Sprintf("https://americas.api.riotgames.com/lol/match/v5/matches/%s", matchID)
+// GetActiveGame returns the game puuid is currently playing, or nil if they
+// are not in a game.
+func (r *RiotAPI) GetActiveGame(ctx context.Context, platform, puuid string) (*ActiveGame, error) {
+	url := fmt.Sprintf("https://%s/lol/spectator/v5/active-games/by-summoner/%s", platformHost(platform), puuid)
 
-	body, err := r.makeRequest(url)
+	body, err := r.makeRequest(ctx, "active-game-by-puuid", url)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var match Match
-	if err := json.Unmarshal(body, &match); err != nil {
+	var game ActiveGame
+	if err := json.Unmarshal(body, &game); err != nil {
 		return nil, err
 	}
 
-	return &match, nil
+	return &game, nil
 }
 
-func (r *RiotAPI) ExtractPlayerData(match *Match, puuid string) *MatchData {
-	for _, participant := range match.Info.Participants {
-		if participant.PUUID == puuid {
-			items := []int{
-				participant.Item0, participant.Item1, participant.Item2,
-				participant.Item3, participant.Item4, participant.Item5, participant.Item6,
-			}
-			itemsJSON, _ := json.Marshal(items)
-
-			return &MatchData{
-				MatchID:      fmt.Sprintf("%d", match.Info.GameID),
-				PUUID:        puuid,
-				Champion:     participant.ChampionName,
-				GameMode:     match.Info.GameMode,
-				GameDuration: match.Info.GameDuration,
-				Win:          participant.Win,
-				Kills:        participant.Kills,
-				Deaths:       participant.Deaths,
-				Assists:      participant.Assists,
-				CreepScore:   participant.TotalMinionsKilled,
-				DamageDealt:  participant.TotalDamageDealt,
-				DamageTaken:  participant.TotalDamageTaken,
-				VisionScore:  participant.VisionScore,
-				GoldEarned:   participant.GoldEarned,
-				Items:        string(itemsJSON),
-				GameCreation: time.Unix(match.Info.GameCreation/1000, 0),
-			}
+// GetLeagueEntries returns puuid's ranked entries on platform; unranked
+// players have none.
+func (r *RiotAPI) GetLeagueEntries(ctx context.Context, platform, puuid string) ([]LeagueEntry, error) {
+	url := fmt.Sprintf("https://%s/lol/league/v4/entries/by-puuid/%s", platformHost(platform), puuid)
+
+	body, err := r.makeRequest(ctx, "league-entries-by-puuid", url)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LeagueEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// PlayerData returns puuid's row of the match, or nil if they did not play
+// in it.
+func (m *Match) PlayerData(puuid string) *MatchData {
+	for idx := range m.Info.Participants {
+		if m.Info.Participants[idx].PUUID == puuid {
+			return m.participantData(&m.Info.Participants[idx])
 		}
 	}
 	return nil
 }
+
+func (m *Match) participantData(participant *MatchParticipant) *MatchData {
+	data := &MatchData{
+		MatchID:      fmt.Sprintf("%d", m.Info.GameID),
+		PUUID:        participant.PUUID,
+		Champion:     participant.ChampionName,
+		TeamPosition: participant.TeamPosition,
+		GameMode:     m.Info.GameMode,
+		QueueID:      m.Info.QueueID,
+		GameDuration: m.Info.GameDuration,
+		Win:          participant.Win,
+		Kills:        participant.Kills,
+		Deaths:       participant.Deaths,
+		Assists:      participant.Assists,
+		CreepScore:   participant.TotalMinionsKilled,
+		DamageDealt:  participant.TotalDamageDealt,
+		DamageTaken:  participant.TotalDamageTaken,
+		VisionScore:  participant.VisionScore,
+		GoldEarned:   participant.GoldEarned,
+		Items:        participant.itemsJSON(),
+		GameCreation: m.CreatedAt(),
+		TeamKills:    m.teamKills(participant),
+	}
+	data.setRates()
+	return data
+}
+
+// teamKills counts the kills of participant's team, or of their pair in
+// Arena, where everyone shares a team ID.
+func (m *Match) teamKills(participant *MatchParticipant) int {
+	kills := 0
+	for idx := range m.Info.Participants {
+		teammate := &m.Info.Participants[idx]
+		if teammate.TeamID == participant.TeamID && teammate.PlayerSubteamID == participant.PlayerSubteamID {
+			kills += teammate.Kills
+		}
+	}
+	return kills
+}
+
+// setRates derives the per-minute stats and kill participation from the
+// match's totals, so short and long games compare fairly.
+func (m *MatchData) setRates() {
+	if m.GameDuration > 0 {
+		minutes := float64(m.GameDuration) / 60
+		m.CSPerMinute = float64(m.CreepScore) / minutes
+		m.DamagePerMinute = float64(m.DamageDealt) / minutes
+		m.GoldPerMinute = float64(m.GoldEarned) / minutes
+		m.VisionPerMinute = float64(m.VisionScore) / minutes
+	}
+	if m.TeamKills > 0 {
+		m.KillParticipation = float64(m.Kills+m.Assists) / float64(m.TeamKills)
+	}
+}
+
+// CreatedAt is when the game was created.
+func (m *Match) CreatedAt() time.Time {
+	return time.Unix(m.Info.GameCreation/1000, 0)
+}
+
+// itemsJSON lists the participant's final items as a JSON array.
+func (p *MatchParticipant) itemsJSON() string {
+	items := []int{p.Item0, p.Item1, p.Item2, p.Item3, p.Item4, p.Item5, p.Item6}
+	itemsJSON, _ := json.Marshal(items)
+	return string(itemsJSON)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParticipantData(t *testing.T) {
+	newMatch := func(gameMode string, duration int, participants ...MatchParticipant) *Match {
+		m := &Match{}
+		m.Info.GameID = 1
+		m.Info.GameMode = gameMode
+		m.Info.GameDuration = duration
+		m.Info.Participants = participants
+		return m
+	}
+
+	tests := []struct {
+		name          string
+		match         *Match
+		wantTeamKills int
+		wantKP        float64
+		wantCSPerMin  float64
+	}{
+		{
+			name: "normal game",
+			match: newMatch("CLASSIC", 1800,
+				MatchParticipant{PUUID: "p", TeamID: 100, Kills: 5, Assists: 7, TotalMinionsKilled: 240},
+				MatchParticipant{PUUID: "ally", TeamID: 100, Kills: 15},
+				MatchParticipant{PUUID: "enemy", TeamID: 200, Kills: 30},
+			),
+			wantTeamKills: 20,
+			wantKP:        0.6,
+			wantCSPerMin:  8,
+		},
+		{
+			// Arena pairs share a team ID; only the player's subteam counts
+			name: "arena",
+			match: newMatch("CHERRY", 900,
+				MatchParticipant{PUUID: "p", TeamID: 0, PlayerSubteamID: 1, Kills: 3, Assists: 1},
+				MatchParticipant{PUUID: "partner", TeamID: 0, PlayerSubteamID: 1, Kills: 1},
+				MatchParticipant{PUUID: "other", TeamID: 0, PlayerSubteamID: 2, Kills: 6},
+				MatchParticipant{PUUID: "other partner", TeamID: 0, PlayerSubteamID: 2, Kills: 4},
+			),
+			wantTeamKills: 4,
+			wantKP:        1,
+		},
+		{
+			name: "no team kills",
+			match: newMatch("CLASSIC", 0,
+				MatchParticipant{PUUID: "p", TeamID: 100, Assists: 2},
+				MatchParticipant{PUUID: "ally", TeamID: 100},
+				MatchParticipant{PUUID: "enemy", TeamID: 200, Kills: 3},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.match.PlayerData("p")
+			if data == nil {
+				t.Fatal("PlayerData returned nil")
+			}
+			if data.TeamKills != tt.wantTeamKills {
+				t.Errorf("team kills = %d, want %d", data.TeamKills, tt.wantTeamKills)
+			}
+			if math.Abs(data.KillParticipation-tt.wantKP) > 1e-9 {
+				t.Errorf("kill participation = %v, want %v", data.KillParticipation, tt.wantKP)
+			}
+			if math.Abs(data.CSPerMinute-tt.wantCSPerMin) > 1e-9 {
+				t.Errorf("CS/min = %v, want %v", data.CSPerMinute, tt.wantCSPerMin)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors for the Riot API failure classes callers care about. Use
+// errors.Is to test for them; errors.As with *APIError gives the status code
+// and, for rate limits, how long to wait.
+var (
+	ErrBadRequest         = errors.New("riot api: bad request")
+	ErrUnauthorized       = errors.New("riot api: unauthorized")
+	ErrNotFound           = errors.New("riot api: not found")
+	ErrRateLimited        = errors.New("riot api: rate limited")
+	ErrServiceUnavailable = errors.New("riot api: service unavailable")
+)
+
+// APIError is returned for every non-200 Riot API response.
+type APIError struct {
+	Endpoint   string
+	StatusCode int
+	// RetryAfter is set for 429 responses.
+	RetryAfter time.Duration
+	kind       error
+}
+
+func newAPIError(endpoint string, resp *http.Response) *APIError {
+	apiErr := &APIError{
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		apiErr.kind = ErrBadRequest
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		apiErr.kind = ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		apiErr.kind = ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.kind = ErrRateLimited
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode >= 500:
+		apiErr.kind = ErrServiceUnavailable
+	}
+
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	if e.kind != nil {
+		return fmt.Sprintf("%v (%s returned status %d)", e.kind, e.Endpoint, e.StatusCode)
+	}
+	return fmt.Sprintf("API request failed with status %d (%s)", e.StatusCode, e.Endpoint)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// retryAfter returns how long Riot asked us to back off, or zero if err is
+// not a rate limit error.
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// riotErrorMessage turns a Riot API error into a message suitable for a slash
+// command response. what describes the thing being looked up.
+func riotErrorMessage(what string, err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fmt.Sprintf("❌ Could not find %s", what)
+	case errors.Is(err, ErrBadRequest):
+		return fmt.Sprintf("❌ Riot rejected the lookup for %s, check the spelling", what)
+	case errors.Is(err, ErrUnauthorized):
+		return "🔑 The bot's Riot API key is invalid or has expired. Ask an admin to rotate it."
+	case errors.Is(err, ErrRateLimited):
+		if wait := retryAfter(err); wait > 0 {
+			return fmt.Sprintf("⏳ Riot is rate limiting the bot, try again in %s", wait.Round(time.Second))
+		}
+		return "⏳ Riot is rate limiting the bot, try again in a minute"
+	case errors.Is(err, ErrServiceUnavailable):
+		return "🔧 Riot's servers are having trouble right now, try again later"
+	default:
+		return fmt.Sprintf("❌ Error looking up %s: %v", what, err)
+	}
+}
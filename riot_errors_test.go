@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorClasses(t *testing.T) {
+	sentinels := []error{ErrBadRequest, ErrUnauthorized, ErrNotFound, ErrRateLimited, ErrServiceUnavailable}
+	tests := []struct {
+		status     int
+		retryAfter string
+		want       error // nil for no class
+		wantWait   time.Duration
+	}{
+		{status: http.StatusBadRequest, want: ErrBadRequest},
+		{status: http.StatusUnauthorized, want: ErrUnauthorized},
+		{status: http.StatusForbidden, want: ErrUnauthorized},
+		{status: http.StatusNotFound, want: ErrNotFound},
+		{status: http.StatusTooManyRequests, retryAfter: "7", want: ErrRateLimited, wantWait: 7 * time.Second},
+		{status: http.StatusTooManyRequests, want: ErrRateLimited, wantWait: 5 * time.Second},
+		{status: http.StatusInternalServerError, want: ErrServiceUnavailable},
+		{status: http.StatusServiceUnavailable, want: ErrServiceUnavailable},
+		{status: http.StatusGatewayTimeout, want: ErrServiceUnavailable},
+		{status: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+		// Callers see the error wrapped with context
+		err := fmt.Errorf("getting match: %w", newAPIError("match-v5", resp))
+
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+				t.Errorf("status %d: errors.Is(%v) = %v", tt.status, sentinel, got)
+			}
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Endpoint != "match-v5" {
+			t.Errorf("status %d: errors.As gave %+v", tt.status, apiErr)
+		}
+		if got := retryAfter(err); got != tt.wantWait {
+			t.Errorf("status %d: retryAfter = %s, want %s", tt.status, got, tt.wantWait)
+		}
+	}
+}
+
+func TestRetryAfterOtherErrors(t *testing.T) {
+	if got := retryAfter(errors.New("connection reset")); got != 0 {
+		t.Errorf("retryAfter of a network error = %s, want 0", got)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// defaultAccountRefreshInterval is how often a player's Riot ID is checked
+// for renames.
+const defaultAccountRefreshInterval = 24 * time.Hour
+
+// refreshRiotID re-reads player's Riot ID from account-v1 when it is due and,
+// if it changed, stores the new one, records the old one in the name history
+// and announces the rename. It returns the player as it should be used for
+// the rest of the check. Only errors that should stop the check (rejected key,
+// rate limiting, shutdown) are returned; a failed lookup is retried on the
+// next check.
+func (gm *GameMonitor) refreshRiotID(ctx context.Context, player TrackedPlayer) (TrackedPlayer, error) {
+	now := time.Now()
+	if now.Sub(player.AccountCheckedAt) < gm.accountRefreshInterval {
+		return player, nil
+	}
+
+	account, err := gm.riotAPI.GetAccountByPUUID(ctx, player.Platform, player.PUUID)
+	if err != nil {
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited) || ctx.Err() != nil {
+			return player, err
+		}
+		log.Printf("Error refreshing Riot ID for %s#%s: %v", player.GameName, player.TagLine, err)
+		return player, nil
+	}
+
+	if account.GameName == "" || (account.GameName == player.GameName && account.TagLine == player.TagLine) {
+		if err := gm.db.MarkAccountChecked(player.PUUID, now); err != nil {
+			log.Printf("Error updating account check time for %s#%s: %v", player.GameName, player.TagLine, err)
+		}
+		return player, nil
+	}
+
+	// Riot IDs are case-insensitive, so a different capitalization is not a
+	// rename; players tracked as typed just get Riot's spelling.
+	if sameRiotID(account.GameName, account.TagLine, player.GameName, player.TagLine) {
+		if err := gm.db.UpdateRiotID(player.PUUID, account.GameName, account.TagLine, now); err != nil {
+			log.Printf("Error updating Riot ID of %s#%s: %v", player.GameName, player.TagLine, err)
+			return player, nil
+		}
+		player.GameName = account.GameName
+		player.TagLine = account.TagLine
+		player.AccountCheckedAt = now
+		return player, nil
+	}
+
+	if err := gm.db.RenamePlayer(player.PUUID, player.GameName, player.TagLine, account.GameName, account.TagLine); err != nil {
+		log.Printf("Error saving new Riot ID for %s#%s: %v", player.GameName, player.TagLine, err)
+		return player, nil
+	}
+	log.Printf("%s#%s is now %s#%s", player.GameName, player.TagLine, account.GameName, account.TagLine)
+
+	gm.announceRename(player, account)
+
+	player.GameName = account.GameName
+	player.TagLine = account.TagLine
+	player.AccountCheckedAt = now
+	return player, nil
+}
+
+// sameRiotID compares two Riot IDs the way Riot does, ignoring case.
+func sameRiotID(gameName, tagLine, otherGameName, otherTagLine string) bool {
+	return strings.EqualFold(gameName, otherGameName) && strings.EqualFold(tagLine, otherTagLine)
+}
+
+func (gm *GameMonitor) announceRename(player TrackedPlayer, account *Account) {
+	channels, err := gm.db.GetPlayerChannels(player.PUUID)
+	if err != nil {
+		log.Printf("Error getting channels for %s#%s: %v", player.GameName, player.TagLine, err)
+		return
+	}
+
+	message := fmt.Sprintf("✏️ **%s#%s** changed their Riot ID to **%s#%s**",
+		player.GameName, player.TagLine, account.GameName, account.TagLine)
+	for _, channelID := range channels {
+		if _, err := gm.discord.ChannelMessageSend(channelID, message); err != nil {
+			discordSendFailures.Inc()
+			log.Printf("Error announcing rename to %s: %v", channelID, err)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// HandlerFunc handles one interaction. Returned errors are reported to the
+// user as an error embed by the router.
+type HandlerFunc func(c *CommandContext) error
+
+// Middleware wraps a handler, e.g. to check permissions before it runs.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// userError is shown to the user as is; any other error is logged and
+// reported as an internal error.
+type userError struct {
+	message string
+}
+
+func (e *userError) Error() string {
+	return e.message
+}
+
+func userErrorf(format string, args ...interface{}) error {
+	return &userError{message: fmt.Sprintf(format, args...)}
+}
+
+// riotError turns a Riot API error into a userError, see riotErrorMessage.
+func riotError(what string, err error) error {
+	return &userError{message: riotErrorMessage(what, err)}
+}
+
+// CommandContext is passed to handlers. It reads options (from the
+// subcommand when there is one) and sends responses, taking care of whether
+// the interaction was already deferred or answered.
+type CommandContext struct {
+	Session     *discordgo.Session
+	Interaction *discordgo.InteractionCreate
+	// Command is nil for component interactions.
+	Command *Command
+	// Name identifies the handler in logs and metrics.
+	Name string
+	// Context carries the invoking user for Riot API admin alerts.
+	Context context.Context
+
+	deferred  bool
+	responded bool
+}
+
+func newCommandContext(s *discordgo.Session, i *discordgo.InteractionCreate, command *Command, name string) *CommandContext {
+	return &CommandContext{
+		Session:     s,
+		Interaction: i,
+		Command:     command,
+		Name:        name,
+		Context:     withRequester(context.Background(), interactionUserID(i)),
+	}
+}
+
+func (c *CommandContext) GuildID() string {
+	return c.Interaction.GuildID
+}
+
+func (c *CommandContext) UserID() string {
+	return interactionUserID(c.Interaction)
+}
+
+// Subcommand returns the invoked subcommand's name, or "".
+func (c *CommandContext) Subcommand() string {
+	options := c.Interaction.ApplicationCommandData().Options
+	if len(options) > 0 && options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		return options[0].Name
+	}
+	return ""
+}
+
+func (c *CommandContext) options() []*discordgo.ApplicationCommandInteractionDataOption {
+	options := c.Interaction.ApplicationCommandData().Options
+	if len(options) > 0 && options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		return options[0].Options
+	}
+	return options
+}
+
+// Option returns the named option, or nil if it was not given.
+func (c *CommandContext) Option(name string) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range c.options() {
+		if opt.Name == name {
+			return opt
+		}
+	}
+	return nil
+}
+
+func (c *CommandContext) StringOption(name string) string {
+	if opt := c.Option(name); opt != nil {
+		return opt.StringValue()
+	}
+	return ""
+}
+
+func (c *CommandContext) IntOption(name string, def int) int {
+	if opt := c.Option(name); opt != nil {
+		return int(opt.IntValue())
+	}
+	return def
+}
+
+func (c *CommandContext) ChannelOption(name string) *discordgo.Channel {
+	if opt := c.Option(name); opt != nil {
+		return opt.ChannelValue(nil)
+	}
+	return nil
+}
+
+// RiotIDOption splits a "PlayerName#TAG" option.
+func (c *CommandContext) RiotIDOption(name string) (gameName, tagLine string, err error) {
+	parts := strings.Split(c.StringOption(name), "#")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", userErrorf("❌ Invalid format. Please use: PlayerName#TAG")
+	}
+	return parts[0], parts[1], nil
+}
+
+// FocusedOption returns the option being typed in an autocomplete
+// interaction, or nil.
+func (c *CommandContext) FocusedOption() *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range c.options() {
+		if opt.Focused {
+			return opt
+		}
+	}
+	return nil
+}
+
+// RespondChoices answers an autocomplete interaction. Discord shows at most
+// 25 choices.
+func (c *CommandContext) RespondChoices(choices []*discordgo.ApplicationCommandOptionChoice) error {
+	if len(choices) > maxAutocompleteChoices {
+		choices = choices[:maxAutocompleteChoices]
+	}
+	return c.Session.InteractionRespond(c.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+func (c *CommandContext) flags() discordgo.MessageFlags {
+	if c.Command != nil && c.Command.Public {
+		return 0
+	}
+	return discordgo.MessageFlagsEphemeral
+}
+
+// Defer acknowledges the interaction so a slow handler is not cut off after
+// Discord's three second limit; the next response replaces the placeholder.
+func (c *CommandContext) Defer() error {
+	if c.deferred || c.responded {
+		return nil
+	}
+	err := c.Session.InteractionRespond(c.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: c.flags(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	c.deferred = true
+	return nil
+}
+
+func (c *CommandContext) Respond(content string) error {
+	return c.send(content, nil, nil)
+}
+
+func (c *CommandContext) RespondEmbed(embed *discordgo.MessageEmbed) error {
+	return c.send("", []*discordgo.MessageEmbed{embed}, nil)
+}
+
+// RespondComplex sends a response with message components, e.g. buttons.
+func (c *CommandContext) RespondComplex(content string, embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
+	return c.send(content, embeds, components)
+}
+
+// FollowUp sends content as a message of its own and returns it, so it can
+// be edited later through the interaction's token (see Backfiller). A
+// deferred interaction's placeholder is used if nothing replaced it yet.
+func (c *CommandContext) FollowUp(content string) (*discordgo.Message, error) {
+	switch {
+	case c.responded:
+		return c.Session.FollowupMessageCreate(c.Interaction.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+			Flags:   c.flags(),
+		})
+	case c.deferred:
+		c.responded = true
+		return c.Session.InteractionResponseEdit(c.Interaction.Interaction, &discordgo.WebhookEdit{Content: &content})
+	default:
+		if err := c.send(content, nil, nil); err != nil {
+			return nil, err
+		}
+		return c.Session.InteractionResponse(c.Interaction.Interaction)
+	}
+}
+
+// UpdateMessage answers a component interaction by replacing the message
+// the component is on, e.g. to turn a page.
+func (c *CommandContext) UpdateMessage(embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
+	c.responded = true
+	return c.Session.InteractionRespond(c.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     embeds,
+			Components: components,
+		},
+	})
+}
+
+func (c *CommandContext) send(content string, embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
+	switch {
+	case c.responded:
+		_, err := c.Session.FollowupMessageCreate(c.Interaction.Interaction, true, &discordgo.WebhookParams{
+			Content:    content,
+			Embeds:     embeds,
+			Components: components,
+			Flags:      c.flags(),
+		})
+		return err
+	case c.deferred:
+		c.responded = true
+		edit := &discordgo.WebhookEdit{Content: &content}
+		if embeds != nil {
+			edit.Embeds = &embeds
+		}
+		if components != nil {
+			edit.Components = &components
+		}
+		_, err := c.Session.InteractionResponseEdit(c.Interaction.Interaction, edit)
+		return err
+	default:
+		c.responded = true
+		return c.Session.InteractionRespond(c.Interaction.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:    content,
+				Embeds:     embeds,
+				Components: components,
+				Flags:      c.flags(),
+			},
+		})
+	}
+}
+
+const maxAutocompleteChoices = 25
+
+// Router dispatches interactions by type: slash commands and autocomplete by
+// command name, message components by the custom ID prefix before ":".
+type Router struct {
+	commands   map[string]*Command
+	components map[string]HandlerFunc
+	middleware []Middleware
+}
+
+func NewRouter(commands []Command, middleware ...Middleware) *Router {
+	r := &Router{
+		commands:   make(map[string]*Command, len(commands)),
+		components: make(map[string]HandlerFunc),
+		middleware: middleware,
+	}
+	for idx := range commands {
+		r.commands[commands[idx].Name] = &commands[idx]
+	}
+	return r
+}
+
+// HandleComponent routes message components whose custom ID is prefix or
+// starts with prefix + ":".
+func (r *Router) HandleComponent(prefix string, handler HandlerFunc) {
+	r.components[prefix] = handler
+}
+
+// Handle is the discordgo InteractionCreate handler.
+func (r *Router) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		name := i.ApplicationCommandData().Name
+		command, ok := r.commands[name]
+		if !ok {
+			log.Printf("Unknown command: %s", name)
+			return
+		}
+		r.run(newCommandContext(s, i, command, name), command.Handler)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		name := i.ApplicationCommandData().Name
+		command, ok := r.commands[name]
+		if !ok || command.Autocomplete == nil {
+			log.Printf("No autocomplete for command: %s", name)
+			return
+		}
+		// Autocomplete must answer with choices, never an error message.
+		c := newCommandContext(s, i, command, name)
+		if err := recoverPanics(command.Autocomplete)(c); err != nil {
+			log.Printf("Error in %s autocomplete: %v", name, err)
+		}
+	case discordgo.InteractionMessageComponent:
+		customID := i.MessageComponentData().CustomID
+		prefix, _, _ := strings.Cut(customID, ":")
+		handler, ok := r.components[prefix]
+		if !ok {
+			log.Printf("Unknown component: %s", customID)
+			return
+		}
+		// Components can share a prefix with a command, e.g. /leaderboard
+		// and its page buttons, so they are named apart in logs and metrics
+		r.run(newCommandContext(s, i, nil, "component:"+prefix), handler)
+	default:
+		log.Printf("Ignoring interaction of type %s", i.Type)
+	}
+}
+
+func (r *Router) run(c *CommandContext, handler HandlerFunc) {
+	for idx := len(r.middleware) - 1; idx >= 0; idx-- {
+		handler = r.middleware[idx](handler)
+	}
+	if err := handler(c); err != nil {
+		log.Printf("Error handling %s: %v", c.Name, err)
+	}
+}
+
+// instrument logs and counts every interaction.
+func instrument(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) error {
+		log.Printf("Interaction received: %s", c.Name)
+		slashCommandInvocations.WithLabelValues(c.Name).Inc()
+
+		start := time.Now()
+		err := next(c)
+		slashCommandDuration.WithLabelValues(c.Name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			slashCommandErrors.WithLabelValues(c.Name).Inc()
+		}
+		return err
+	}
+}
+
+// respondErrors shows handler errors to the user as an error embed. Internal
+// errors get a generic message, since they can contain SQL or API details,
+// and are passed on to be logged.
+func respondErrors(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) error {
+		err := next(c)
+		if err == nil {
+			return nil
+		}
+
+		var userErr *userError
+		message := "❌ Something went wrong, please try again later"
+		if errors.As(err, &userErr) {
+			message = userErr.message
+			err = nil
+		}
+
+		if sendErr := c.RespondEmbed(errorEmbed(message)); sendErr != nil {
+			log.Printf("Error sending error response for %s: %v", c.Name, sendErr)
+		}
+		return err
+	}
+}
+
+func errorEmbed(message string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Description: message,
+		Color:       0xFF0000,
+	}
+}
+
+var errPanic = errors.New("handler panicked")
+
+// recoverPanics turns a panicking handler into an error so one bad
+// interaction cannot take the bot down.
+func recoverPanics(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Panic handling %s: %v\n%s", c.Name, r, debug.Stack())
+				err = fmt.Errorf("%w: %v", errPanic, r)
+			}
+		}()
+		return next(c)
+	}
+}
+
+// checkPermissions enforces a command's GuildOnly and Permission settings.
+// DefaultMemberPermissions only hides commands by default; guilds can
+// override it, so handlers never rely on it alone.
+func checkPermissions(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) error {
+		if command := c.Command; command != nil {
+			if command.GuildOnly && c.GuildID() == "" {
+				return userErrorf("❌ This command can only be used in a server")
+			}
+			if command.Permission != nil && !command.Permission(c.Interaction) {
+				return userErrorf("❌ You don't have permission to use this command")
+			}
+		}
+		return next(c)
+	}
+}
+
+// deferSlow defers commands marked Defer before their handler runs.
+func deferSlow(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) error {
+		if c.Command != nil && c.Command.Defer {
+			if err := c.Defer(); err != nil {
+				return err
+			}
+		}
+		return next(c)
+	}
+}
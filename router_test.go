@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// recordingTransport answers every Discord API request with 204 and keeps
+// the interaction responses that were sent.
+type recordingTransport struct {
+	responses []discordgo.InteractionResponse
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "/callback") {
+		var response discordgo.InteractionResponse
+		if err := json.NewDecoder(req.Body).Decode(&response); err != nil {
+			return nil, err
+		}
+		t.responses = append(t.responses, response)
+	}
+	return &http.Response{
+		StatusCode: http.StatusNoContent,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+// routeTestCommand runs handler as /test through the production middleware
+// and returns the embed description sent to the user, or "" if none was.
+func routeTestCommand(t *testing.T, command Command, member *discordgo.Member) string {
+	t.Helper()
+
+	transport := &recordingTransport{}
+	session, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Client = &http.Client{Transport: transport}
+
+	command.ApplicationCommand = &discordgo.ApplicationCommand{Name: "test"}
+	r := NewRouter([]Command{command}, router.middleware...)
+	r.Handle(session, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:      "1",
+		Token:   "token",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild",
+		Member:  member,
+		Data:    discordgo.ApplicationCommandInteractionData{Name: "test"},
+	}})
+
+	switch len(transport.responses) {
+	case 0:
+		return ""
+	case 1:
+		data := transport.responses[0].Data
+		if data == nil || len(data.Embeds) != 1 {
+			t.Fatalf("response = %+v, want one embed", data)
+		}
+		return data.Embeds[0].Description
+	default:
+		t.Fatalf("sent %d responses, want at most one", len(transport.responses))
+		return ""
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	const internal = "❌ Something went wrong, please try again later"
+	member := &discordgo.Member{User: &discordgo.User{ID: "user"}}
+
+	tests := []struct {
+		name    string
+		command Command
+		want    string
+	}{
+		{
+			name:    "success",
+			command: Command{Handler: func(c *CommandContext) error { return nil }},
+			want:    "",
+		},
+		{
+			name: "user error",
+			command: Command{Handler: func(c *CommandContext) error {
+				return userErrorf("❌ Player not found")
+			}},
+			want: "❌ Player not found",
+		},
+		{
+			name: "wrapped user error",
+			command: Command{Handler: func(c *CommandContext) error {
+				return riotError("player", ErrNotFound)
+			}},
+			want: riotErrorMessage("player", ErrNotFound),
+		},
+		{
+			name: "internal error",
+			command: Command{Handler: func(c *CommandContext) error {
+				return errors.New("pq: relation \"players\" does not exist")
+			}},
+			want: internal,
+		},
+		{
+			name: "panic",
+			command: Command{Handler: func(c *CommandContext) error {
+				panic("boom")
+			}},
+			want: internal,
+		},
+		{
+			name: "permission denied",
+			command: Command{
+				Handler: func(c *CommandContext) error {
+					t.Error("handler ran without permission")
+					return nil
+				},
+				Permission: func(i *discordgo.InteractionCreate) bool { return false },
+			},
+			want: "❌ You don't have permission to use this command",
+		},
+		{
+			name: "permission granted",
+			command: Command{
+				Handler:    func(c *CommandContext) error { return userErrorf("ran") },
+				Permission: func(i *discordgo.InteractionCreate) bool { return true },
+			},
+			want: "ran",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeTestCommand(t, tt.command, member); got != tt.want {
+				t.Errorf("error message = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"net/url"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeFormat matches CURRENT_TIMESTAMP, so times written by the bot
+// and column defaults compare correctly as text.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999"
+
+var sqliteDialect = dialect{
+	migrations: "migrations/sqlite",
+	bindArgs:   sqliteArgs,
+	encodeJSON: gzipJSON,
+	decodeJSON: gunzipJSON,
+}
+
+// NewSQLiteStore opens (creating if needed) the SQLite database at path.
+// It needs no server, which suits small deployments and tests.
+func NewSQLiteStore(path string) (*Database, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time; the monitor's workers queue here
+	// instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Database{db: db, dialect: sqliteDialect}, nil
+}
+
+// sqliteArgs stores times as UTC text in CURRENT_TIMESTAMP's format. The
+// driver's default includes the local zone, which breaks comparisons.
+func sqliteArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for idx, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[idx] = v.UTC().Format(sqliteTimeFormat)
+		case sql.NullTime:
+			if v.Valid {
+				converted[idx] = v.Time.UTC().Format(sqliteTimeFormat)
+			}
+		default:
+			converted[idx] = arg
+		}
+	}
+	return converted
+}
+
+// gzipJSON compresses payloads, which SQLite would otherwise store as is.
+func gzipJSON(doc []byte) (interface{}, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(doc); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipJSON(stored []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// /stats view option values.
+const (
+	statsViewOverview  = "overview"
+	statsViewChampions = "champions"
+	statsViewRoles     = "roles"
+	statsViewQueues    = "queues"
+	statsViewGames     = "games"
+)
+
+var statsViewChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "Overview", Value: statsViewOverview},
+	{Name: "Champions", Value: statsViewChampions},
+	{Name: "Roles", Value: statsViewRoles},
+	{Name: "Queues", Value: statsViewQueues},
+	{Name: "Best and worst games", Value: statsViewGames},
+}
+
+const (
+	defaultStatsDays = 7
+	maxStatsDays     = 365
+)
+
+var minStatsDays = 1.0
+
+const (
+	// statsTableRows is how many champions the champions view lists.
+	statsTableRows = 15
+
+	// statsGamesShown is how many best and worst games the games view lists.
+	statsGamesShown = 3
+)
+
+// roleNames maps match-v5 teamPosition values to display names.
+var roleNames = map[string]string{
+	"TOP":     "Top",
+	"JUNGLE":  "Jungle",
+	"MIDDLE":  "Mid",
+	"BOTTOM":  "Bot",
+	"UTILITY": "Support",
+}
+
+// roleName renders a teamPosition. It is empty in modes without lanes, and
+// in games stored before roles were.
+func roleName(position string) string {
+	if position == "" {
+		return "No role"
+	}
+	if name, ok := roleNames[position]; ok {
+		return name
+	}
+	return gameModeName(position)
+}
+
+func (g *StatGroup) WinRate() float64 {
+	return float64(g.Wins) / float64(max(g.Games, 1)) * 100
+}
+
+func (g *StatGroup) KDA() float64 {
+	return float64(g.Kills+g.Assists) / float64(max(g.Deaths, 1))
+}
+
+// add merges other into g, weighting the averages by the games they cover.
+func (g *StatGroup) add(other StatGroup) {
+	average := func(a float64, aGames int, b float64, bGames int) float64 {
+		if aGames+bGames == 0 {
+			return 0
+		}
+		return (a*float64(aGames) + b*float64(bGames)) / float64(aGames+bGames)
+	}
+	g.CSPerMinute = average(g.CSPerMinute, g.Games, other.CSPerMinute, other.Games)
+	g.DamagePerMinute = average(g.DamagePerMinute, g.Games, other.DamagePerMinute, other.Games)
+	g.GoldPerMinute = average(g.GoldPerMinute, g.Games, other.GoldPerMinute, other.Games)
+	g.VisionPerMinute = average(g.VisionPerMinute, g.Games, other.VisionPerMinute, other.Games)
+	g.KillParticipation = average(g.KillParticipation, g.KillParticipationGames,
+		other.KillParticipation, other.KillParticipationGames)
+
+	g.KillParticipationGames += other.KillParticipationGames
+	g.Games += other.Games
+	g.Wins += other.Wins
+	g.Kills += other.Kills
+	g.Deaths += other.Deaths
+	g.Assists += other.Assists
+	g.CreepScore += other.CreepScore
+	g.DamageDealt += other.DamageDealt
+	g.GoldEarned += other.GoldEarned
+	g.VisionScore += other.VisionScore
+}
+
+func handleStatsCommand(c *CommandContext) error {
+	gameName, tagLine, err := c.RiotIDOption("summoner")
+	if err != nil {
+		return err
+	}
+
+	days := c.IntOption("days", defaultStatsDays)
+	queue := findQueueFilter(c.StringOption("queue"))
+
+	filter := StatsFilter{Since: time.Now().AddDate(0, 0, -days)}
+	period := fmt.Sprintf("Last %d days", days)
+	if queue != nil {
+		filter.QueueIDs = queue.QueueIDs
+		period = fmt.Sprintf("%s, %s", queue.Name, period)
+	}
+
+	player, err := guildPlayerByRiotID(c.GuildID(), gameName, tagLine)
+	if err != nil {
+		return err
+	}
+	filter.PUUIDs = []string{player.PUUID}
+
+	totals, err := db.GetStatGroups(filter, StatsTotal, 0)
+	if err != nil {
+		return fmt.Errorf("getting stats: %w", err)
+	}
+	if len(totals) == 0 || totals[0].Games == 0 {
+		return c.Respond(fmt.Sprintf("📊 No games found for %s#%s (%s)", player.GameName, player.TagLine, period))
+	}
+
+	view := c.StringOption("view")
+	var embed *discordgo.MessageEmbed
+	switch view {
+	case statsViewChampions:
+		embed, err = championStatsView(filter)
+	case statsViewRoles:
+		embed, err = roleStatsView(filter)
+	case statsViewQueues:
+		embed, err = queueStatsView(filter)
+	case statsViewGames:
+		embed, err = gamesStatsView(filter, totals[0])
+	default:
+		embed, err = overviewStatsView(filter, totals[0], queue == nil)
+	}
+	if err != nil {
+		return fmt.Errorf("getting %s stats: %w", view, err)
+	}
+	embed.Title = fmt.Sprintf("📊 %s for %s#%s (%s)", embed.Title, player.GameName, player.TagLine, period)
+	embed.Color = 0x0099FF
+
+	// Old names still resolve, so say who the player is now.
+	if history, err := db.GetNameHistory(player.PUUID); err != nil {
+		log.Printf("Error getting name history for %s#%s: %v", player.GameName, player.TagLine, err)
+	} else if len(history) > 0 {
+		names := make([]string, 0, len(history))
+		for _, change := range history {
+			names = append(names, change.GameName+"#"+change.TagLine)
+		}
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: "Previously known as " + strings.Join(names, ", "),
+		}
+	}
+
+	return c.RespondEmbed(embed)
+}
+
+// overviewStatsView sums up every game, listing the queues played unless
+// the command was limited to some.
+func overviewStatsView(filter StatsFilter, totals StatGroup, allQueues bool) (*discordgo.MessageEmbed, error) {
+	games := float64(totals.Games)
+	embed := &discordgo.MessageEmbed{
+		Title: "Stats",
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Games Played",
+				Value:  fmt.Sprintf("%d", totals.Games),
+				Inline: true,
+			},
+			{
+				Name:   "Win Rate",
+				Value:  fmt.Sprintf("%.1f%% (%d wins)", totals.WinRate(), totals.Wins),
+				Inline: true,
+			},
+			{
+				Name:   "Average KDA",
+				Value:  fmt.Sprintf("%.1f/%.1f/%.1f (%.2f)", float64(totals.Kills)/games, float64(totals.Deaths)/games, float64(totals.Assists)/games, totals.KDA()),
+				Inline: true,
+			},
+			{
+				Name:   "Average CS",
+				Value:  fmt.Sprintf("%.1f (%.1f/min)", float64(totals.CreepScore)/games, totals.CSPerMinute),
+				Inline: true,
+			},
+			{
+				Name:   "Average Damage",
+				Value:  fmt.Sprintf("%s (%.0f/min)", formatThousands(totals.DamageDealt/totals.Games), totals.DamagePerMinute),
+				Inline: true,
+			},
+			{
+				Name:   "Average Gold",
+				Value:  fmt.Sprintf("%s (%.0f/min)", formatThousands(totals.GoldEarned/totals.Games), totals.GoldPerMinute),
+				Inline: true,
+			},
+			{
+				Name:   "Average Vision",
+				Value:  fmt.Sprintf("%.1f (%.2f/min)", float64(totals.VisionScore)/games, totals.VisionPerMinute),
+				Inline: true,
+			},
+		},
+	}
+
+	if totals.KillParticipationGames > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Kill Participation",
+			Value:  fmt.Sprintf("%.0f%%", totals.KillParticipation*100),
+			Inline: true,
+		})
+	}
+
+	if allQueues {
+		queues, err := queueStatGroups(filter)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, 0, len(queues))
+		for _, queue := range queues {
+			parts = append(parts, fmt.Sprintf("%s %d", queue.Key, queue.Games))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Queues",
+			Value: strings.Join(parts, " • "),
+		})
+	}
+	return embed, nil
+}
+
+func championStatsView(filter StatsFilter) (*discordgo.MessageEmbed, error) {
+	champions, err := db.GetStatGroups(filter, GroupByChampion, statsTableRows)
+	if err != nil {
+		return nil, err
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "Champion stats",
+		Description: statsTable("Champion", champions),
+	}, nil
+}
+
+func roleStatsView(filter StatsFilter) (*discordgo.MessageEmbed, error) {
+	roles, err := db.GetStatGroups(filter, GroupByRole, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	unknown := false
+	for idx := range roles {
+		unknown = unknown || roles[idx].Key == ""
+		roles[idx].Key = roleName(roles[idx].Key)
+	}
+
+	description := statsTable("Role", roles)
+	if unknown {
+		description += "\n*No role* covers modes without lanes, and games stored before roles were."
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "Role stats",
+		Description: description,
+	}, nil
+}
+
+func queueStatsView(filter StatsFilter) (*discordgo.MessageEmbed, error) {
+	queues, err := queueStatGroups(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "Queue stats",
+		Description: statsTable("Queue", queues),
+	}, nil
+}
+
+// gamesStatsView lists the best and worst games by KDA ratio. With few
+// games the worst list only holds those not already among the best.
+func gamesStatsView(filter StatsFilter, totals StatGroup) (*discordgo.MessageEmbed, error) {
+	best, err := db.GetGamesByPerformance(filter, true, statsGamesShown)
+	if err != nil {
+		return nil, err
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: "Best and worst games",
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "🏆 Best games", Value: gameLines(best)},
+		},
+	}
+
+	if worstCount := min(statsGamesShown, totals.Games-statsGamesShown); worstCount > 0 {
+		worst, err := db.GetGamesByPerformance(filter, false, worstCount)
+		if err != nil {
+			return nil, err
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "💀 Worst games",
+			Value: gameLines(worst),
+		})
+	}
+	return embed, nil
+}
+
+// gameLines renders one line per match, e.g.
+// "✅ **Ahri** 12/2/8 (10.00 KDA) • Ranked Solo/Duo • <date>".
+func gameLines(matches []MatchData) string {
+	lines := make([]string, 0, len(matches))
+	for idx := range matches {
+		match := &matches[idx]
+		result := "❌"
+		if match.Win {
+			result = "✅"
+		}
+		kda := float64(match.Kills+match.Assists) / float64(max(match.Deaths, 1))
+		lines = append(lines, fmt.Sprintf("%s **%s** %d/%d/%d (%.2f KDA) • %s • <t:%d:d>",
+			result, match.Champion, match.Kills, match.Deaths, match.Assists, kda,
+			matchQueueName(match), match.GameCreation.Unix()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// queueStatGroups totals filter's matches per queue name, most played first.
+// Several queue IDs can share a name, e.g. both Arena queues.
+func queueStatGroups(filter StatsFilter) ([]StatGroup, error) {
+	groups, err := db.GetStatGroups(filter, GroupByQueue, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var queues []StatGroup
+	byName := make(map[string]int)
+	for _, group := range groups {
+		queueID, _ := strconv.Atoi(group.Key)
+		name := matchQueueName(&MatchData{QueueID: queueID, GameMode: group.GameMode})
+		if idx, ok := byName[name]; ok {
+			queues[idx].add(group)
+			continue
+		}
+		group.Key = name
+		byName[name] = len(queues)
+		queues = append(queues, group)
+	}
+
+	sort.SliceStable(queues, func(a, b int) bool {
+		return queues[a].Games > queues[b].Games
+	})
+	return queues, nil
+}
+
+// statsTable lays groups out as a monospace table whose first column,
+// headed label, holds their keys.
+func statsTable(label string, groups []StatGroup) string {
+	width := utf8.RuneCountInString(label)
+	for _, group := range groups {
+		width = max(width, utf8.RuneCountInString(group.Key))
+	}
+
+	var table strings.Builder
+	table.WriteString("```\n")
+	fmt.Fprintf(&table, "%-*s %5s %4s %5s %5s %4s\n", width, label, "Games", "WR", "KDA", "CS/m", "DPM")
+	for idx := range groups {
+		group := &groups[idx]
+		fmt.Fprintf(&table, "%-*s %5d %3.0f%% %5.2f %5.1f %4.0f\n", width, group.Key,
+			group.Games, group.WinRate(), group.KDA(), group.CSPerMinute, group.DamagePerMinute)
+	}
+	table.WriteString("```")
+	return table.String()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStatGroupAdd(t *testing.T) {
+	tests := []struct {
+		name         string
+		groups       []StatGroup
+		wantGames    int
+		wantCSPerMin float64
+		wantKP       float64
+		wantKPGames  int
+	}{
+		{
+			name: "weighted by games",
+			groups: []StatGroup{
+				{Games: 3, CSPerMinute: 8, KillParticipation: 0.6, KillParticipationGames: 3},
+				{Games: 1, CSPerMinute: 4, KillParticipation: 0.2, KillParticipationGames: 1},
+			},
+			wantGames:    4,
+			wantCSPerMin: 7,
+			wantKP:       0.5,
+			wantKPGames:  4,
+		},
+		{
+			name: "kill participation only over known games",
+			groups: []StatGroup{
+				{Games: 2, CSPerMinute: 6, KillParticipation: 0.4, KillParticipationGames: 1},
+				{Games: 8, CSPerMinute: 6},
+			},
+			wantGames:    10,
+			wantCSPerMin: 6,
+			wantKP:       0.4,
+			wantKPGames:  1,
+		},
+		{
+			name:   "empty groups",
+			groups: []StatGroup{{}, {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var total StatGroup
+			for _, group := range tt.groups {
+				total.add(group)
+			}
+			if total.Games != tt.wantGames || total.KillParticipationGames != tt.wantKPGames {
+				t.Errorf("games %d (%d with KP), want %d (%d with KP)", total.Games, total.KillParticipationGames,
+					tt.wantGames, tt.wantKPGames)
+			}
+			if math.Abs(total.CSPerMinute-tt.wantCSPerMin) > 1e-9 {
+				t.Errorf("CS/min = %v, want %v", total.CSPerMinute, tt.wantCSPerMin)
+			}
+			if math.Abs(total.KillParticipation-tt.wantKP) > 1e-9 {
+				t.Errorf("kill participation = %v, want %v", total.KillParticipation, tt.wantKP)
+			}
+		})
+	}
+}
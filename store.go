@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Store is everything the bot persists. Database implements it on
+// PostgreSQL (NewPostgresStore) and SQLite (NewSQLiteStore); see the
+// methods there for details.
+type Store interface {
+	Close() error
+
+	// Schema migrations
+	Migrate(ctx context.Context) ([]MigrationStatus, error)
+	Migrations(ctx context.Context) ([]MigrationStatus, error)
+
+	// Tracked players
+	AddTrackedPlayer(player *TrackedPlayer) error
+	GetTrackedPlayers() ([]TrackedPlayer, error)
+	GetTrackedPlayer(puuid string) (*TrackedPlayer, error)
+	UpdateLastMatchID(puuid, matchID string, matchTime time.Time) error
+	UpdatePollSchedule(puuid, state string, nextCheckAt time.Time) error
+	RenamePlayer(puuid, oldGameName, oldTagLine, gameName, tagLine string) error
+	UpdateRiotID(puuid, gameName, tagLine string, checkedAt time.Time) error
+	MarkAccountChecked(puuid string, checkedAt time.Time) error
+	GetNameHistory(puuid string) ([]NameChange, error)
+
+	// Guilds
+	GetGuildPlayers(guildID string) ([]TrackedPlayer, error)
+	GetGuildPlayerByRiotID(guildID, gameName, tagLine string) (*TrackedPlayer, error)
+	SearchGuildPlayers(guildID, query string, limit int) ([]TrackedPlayer, error)
+	AddGuildPlayer(guildID, puuid, addedBy string) error
+	RemoveGuildPlayer(guildID, puuid string) (bool, error)
+	AdoptUnscopedPlayers(guildID string) (int64, error)
+	GetPlayerChannels(puuid string) ([]string, error)
+	GetGuildSettings(guildID string) (*GuildSettings, error)
+	SetGuildChannel(guildID, channelID string) error
+
+	// Matches
+	SaveMatch(match *Match) error
+	GetMatch(matchID string) (*Match, error)
+	GetStoredMatchIDs() ([]string, error)
+	AddMatchData(match *MatchData) error
+	UpdateMatchData(match *MatchData) error
+	GetStatGroups(filter StatsFilter, grouping StatsGrouping, limit int) ([]StatGroup, error)
+	GetGamesByPerformance(filter StatsFilter, best bool, limit int) ([]MatchData, error)
+	GetLastGameTime(puuid string) (time.Time, error)
+
+	// Match queue
+	EnqueueMatch(puuid, matchID string) (entry *QueuedMatch, inserted bool, err error)
+	GetPendingMatches(puuid string) ([]QueuedMatch, error)
+	MarkMatchProcessed(puuid, matchID string) error
+	RecordMatchFailure(puuid, matchID, status, lastError string, attempts int, nextAttemptAt time.Time) error
+	GetFailedMatches(limit int) ([]QueuedMatch, error)
+	RetryFailedMatch(matchID string) (int64, error)
+
+	// Backfills
+	AddBackfillJob(job *BackfillJob) error
+	GetActiveBackfill(puuid string) (*BackfillJob, error)
+	GetPendingBackfills() ([]BackfillJob, error)
+	UpdateBackfillProgress(id, nextIndex, stored int) error
+	FinishBackfill(id int, status, lastError string) error
+
+	// Live games
+	AddLiveGame(game *LiveGame) error
+	GetLiveGames(puuid, gameID string) ([]LiveGame, error)
+	RemoveLiveGame(puuid, gameID string) error
+	RemoveStaleLiveGames(olderThan time.Time) error
+
+	// Ranks
+	AddRankSnapshot(snapshot *RankSnapshot) error
+	GetRankSnapshotBefore(puuid, queueType string, t time.Time) (*RankSnapshot, error)
+	GetRankSnapshotAfter(puuid, queueType string, t time.Time) (*RankSnapshot, error)
+}
+
+var _ Store = (*Database)(nil)